@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"maps"
+	"slices"
+)
+
+// Aliases maps the coordinates found in build files to the catalog aliases they are rewritten to.
+type Aliases struct {
+	// catalog is the name of the catalog accessor, e.g. libs
+	catalog string
+	// libraries is keyed by "group:name"
+	libraries map[string]string
+	// perFile overrides libraries for the declarations of the given build file,
+	// keyed by "group:name@version" with the version as written
+	perFile map[string]map[string]string
+	// plugins is keyed by plugin id
+	plugins map[string]string
+	// routes are the catalogs entries were split into, if any
+	routes CatalogRoutes
+	// unresolved lists the version variables of each build file that could not be resolved,
+	// whose declarations are left as they are
+	unresolved map[string][]string
+	// versionKeys are the [versions] keys by the name they were derived from and their value,
+	// for the versions written outside declarations, e.g. useVersion("1.0")
+	versionKeys map[[2]string]string
+}
+
+func newAliases(catalog string) Aliases {
+	return Aliases{
+		catalog:   catalog,
+		libraries: make(map[string]string),
+		perFile:   make(map[string]map[string]string),
+		plugins:   make(map[string]string),
+	}
+}
+
+func (a Aliases) library(path string, lib StrictLibrary) string {
+	coordinate := lib.coordinate()
+	if alias, ok := a.perFile[path][coordinate+"@"+lib.Version]; ok {
+		return alias
+	}
+	if alias, ok := a.libraries[coordinate]; ok {
+		return alias
+	}
+	return catalogSafeKey(lib)
+}
+
+// isUnresolved tells whether the declaration in the build file at path refers to a version variable that could not be resolved.
+func (a Aliases) isUnresolved(path string, version string, quoted bool) bool {
+	name, ok := versionVariableOf(version, quoted)
+	return ok && slices.Contains(a.unresolved[path], name)
+}
+
+func (a Aliases) setForFile(path string, lib StrictLibrary, alias string) {
+	if _, ok := a.perFile[path]; !ok {
+		a.perFile[path] = make(map[string]string)
+	}
+	a.perFile[path][lib.coordinate()+"@"+lib.Declared] = alias
+}
+
+func (a Aliases) plugin(plugin Plugin) string {
+	if alias, ok := a.plugins[plugin.Id]; ok {
+		return alias
+	}
+	return catalogSafeKeyPlugin(plugin)
+}
+
+// libraryCatalog returns the name of the catalog holding the library alias.
+func (a Aliases) libraryCatalog(alias string) string {
+	if name, ok := a.routes.libraries[alias]; ok {
+		return name
+	}
+	return a.catalog
+}
+
+func (a Aliases) pluginCatalog(alias string) string {
+	if name, ok := a.routes.plugins[alias]; ok {
+		return name
+	}
+	return a.catalog
+}
+
+func (a Aliases) versionCatalog(key string) string {
+	if name, ok := a.routes.versions[key]; ok {
+		return name
+	}
+	return a.catalog
+}
+
+// catalogNames returns the names of all catalogs entries are accessed by.
+func (a Aliases) catalogNames() []string {
+	names := []string{a.catalog}
+	for _, routes := range []map[string]string{a.routes.libraries, a.routes.plugins, a.routes.versions} {
+		for _, name := range routes {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func libraryAccessor(catalog string, alias string) string {
+	return catalog + "." + accessorSeparators.Replace(alias)
+}
+
+func pluginAccessor(catalog string, alias string) string {
+	return catalog + ".plugins." + accessorSeparators.Replace(alias)
+}
+
+// indexLibraryAliases maps "group:name" to the alias already used for it in the catalog.
+// Both `module = "group:name"` and `group = "group", name = "name"` notations are recognized.
+func indexLibraryAliases(libraries Libraries) map[string]string {
+	index := make(map[string]string)
+	for _, alias := range slices.Sorted(maps.Keys(libraries)) {
+		coordinate, ok := libraryCoordinate(libraries[alias])
+		if !ok {
+			continue
+		}
+		if _, ok := index[coordinate]; !ok {
+			index[coordinate] = alias
+		}
+	}
+	return index
+}
+
+// libraryCoordinate returns "group:name" of a catalog library.
+func libraryCoordinate(library LooseLibrary) (string, bool) {
+	if module, ok := library["module"].(string); ok {
+		return module, true
+	}
+	if group, ok := library["group"].(string); ok {
+		name, _ := library["name"].(string)
+		return group + ":" + name, true
+	}
+	return "", false
+}
+
+// indexPluginAliases maps plugin ids to the alias already used for them in the catalog.
+func indexPluginAliases(plugins Plugins) map[string]string {
+	index := make(map[string]string)
+	for _, alias := range slices.Sorted(maps.Keys(plugins)) {
+		if _, ok := index[plugins[alias].Id]; !ok {
+			index[plugins[alias].Id] = alias
+		}
+	}
+	return index
+}
+
+// versionRefOf returns the key of a `version.ref` entry.
+func versionRefOf(version any) (string, bool) {
+	if loose, ok := version.(LooseLibrary); ok && len(loose) == 1 {
+		ref, ok := loose["ref"].(string)
+		return ref, ok
+	}
+	return "", false
+}
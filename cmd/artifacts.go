@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Artifact is what a declaration selects of a module beyond its coordinates, which the catalog cannot hold.
+type Artifact struct {
+	Classifier string
+	// Type is the artifact type, e.g. aar for "g:a:1.0@aar" or ext = "aar"
+	Type string
+	// Configuration is the configuration of the module the declaration depends on, e.g. configuration = "runtime"
+	Configuration string
+}
+
+var mapArgumentExtractor = regexp.MustCompile(`\b(classifier|ext|configuration)\s*[=:]\s*["']([^"'\r\n]*)["']`)
+var transitiveExtractor = regexp.MustCompile(`\b(?:isTransitive|transitive)\b`)
+
+// parseMapArguments reads the classifier, ext and configuration arguments of a map notation declaration.
+func parseMapArguments(arguments string) Artifact {
+	artifact := Artifact{}
+	for _, match := range mapArgumentExtractor.FindAllStringSubmatch(arguments, -1) {
+		switch match[1] {
+		case "classifier":
+			artifact.Classifier = match[2]
+		case "ext":
+			artifact.Type = match[2]
+		case "configuration":
+			artifact.Configuration = match[2]
+		}
+	}
+	return artifact
+}
+
+// variant wraps accessor in variantOf when the artifact has a classifier or a type.
+func (a Artifact) variant(dialect Dialect, accessor string, quote string) string {
+	selectors := make([]string, 0)
+	if a.Classifier != "" {
+		selectors = append(selectors, fmt.Sprintf("classifier(%s)", dialect.quote(a.Classifier, quote)))
+	}
+	if a.Type != "" {
+		selectors = append(selectors, fmt.Sprintf("artifactType(%s)", dialect.quote(a.Type, quote)))
+	}
+	if len(selectors) == 0 {
+		return accessor
+	}
+	return fmt.Sprintf("variantOf(%s) { %s }", accessor, strings.Join(selectors, "; "))
+}
+
+// statements returns what the closure of the declaration must set for the artifact:
+// the target configuration, and no transitive dependencies for an artifact only notation, unless closure already sets it.
+func (a Artifact) statements(dialect Dialect, quote string, closure string) []string {
+	statements := make([]string, 0)
+	if a.Configuration != "" {
+		statements = append(statements, "targetConfiguration = "+dialect.quote(a.Configuration, quote))
+	}
+	if a.Type != "" && !transitiveExtractor.MatchString(closure) {
+		if dialect == Kotlin {
+			statements = append(statements, "isTransitive = false")
+		} else {
+			statements = append(statements, "transitive = false")
+		}
+	}
+	return statements
+}
+
+// closureFollowing returns where the closure following a declaration opens in following, if any.
+func closureFollowing(following string) (int, bool) {
+	open := len(following) - len(strings.TrimLeft(following, " \t"))
+	return open, open < len(following) && following[open] == '{'
+}
+
+// replaceDeclarations is regexp.ReplaceAllStringFunc whose replacement may also consume the text following a match,
+// e.g. to add statements to the closure of the declaration.
+func replaceDeclarations(re *regexp.Regexp, content string, replace func(match string, following string) (string, int)) string {
+	var builder strings.Builder
+	last := 0
+	for _, location := range re.FindAllStringIndex(content, -1) {
+		if location[0] < last {
+			continue
+		}
+		builder.WriteString(content[last:location[0]])
+		replacement, consumed := replace(content[location[0]:location[1]], content[location[1]:])
+		builder.WriteString(replacement)
+		last = location[1] + consumed
+	}
+	builder.WriteString(content[last:])
+	return builder.String()
+}
+
+// callWithClosure renders a declaration whose closure must hold statements,
+// inserting them into the closure following it or adding a closure.
+func callWithClosure(dialect Dialect, name, open, arg, close string, statements []string, following string) (string, int) {
+	if len(statements) == 0 {
+		return dialect.call(name, open, arg, close), 0
+	}
+	if dialect == Groovy && !strings.Contains(open, "(") {
+		// implementation libs.x { } would pass the closure to the accessor
+		open, close = "(", ")"
+	}
+	call := dialect.call(name, open, arg, close)
+	if brace, ok := closureFollowing(following); ok {
+		return call + following[:brace] + "{ " + strings.Join(statements, "; ") + ";", brace + 1
+	}
+	return call + " { " + strings.Join(statements, "; ") + " }", 0
+}
@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// BuilderCatalog is a catalog declared with the builder API of the settings file,
+// e.g. create("libs") { library("okhttp", "com.squareup.okhttp3", "okhttp").version("4.12.0") }.
+type BuilderCatalog struct {
+	Name string
+	// From is the TOML file the declaration imports too, if any, as written
+	From string
+	// declaration delimits the whole declaration in the settings file, and body the text between its braces
+	declaration [2]int
+	body        [2]int
+}
+
+// create("libs") { ... } in Kotlin, libs { ... } in Groovy
+var builderCatalogExtractor = regexp.MustCompile(`(?:\bcreate\(\s*["'](?P<created>\w+)["']\s*\)|\b(?P<named>\w+))\s*\{`)
+var builderCallExtractor = regexp.MustCompile(`\b(version|library|plugin|bundle|from)\s*\(`)
+var builderChainExtractor = regexp.MustCompile(`^\s*\.\s*(version|versionRef|withoutVersion)\s*(\(|\{)`)
+var builderSeparatorExtractor = regexp.MustCompile(`^[\s;]*$`)
+var builderFilesExtractor = regexp.MustCompile(`^files\(\s*["']([^"'\r\n]+)["']\s*\)$`)
+
+// findBuilderCatalogs collects the catalogs declared in the versionCatalogs { } blocks of a settings file.
+func findBuilderCatalogs(content string) []BuilderCatalog {
+	stripped := stripComments(content)
+	catalogs := make([]BuilderCatalog, 0)
+	for _, location := range versionCatalogsExtractor.FindAllStringIndex(stripped, -1) {
+		open := location[1] - 1
+		body := blockBody(stripped, open)
+		for pos := 0; pos < len(body); {
+			match := builderCatalogExtractor.FindStringSubmatchIndex(body[pos:])
+			if match == nil {
+				break
+			}
+			name := submatch(builderCatalogExtractor, stringsOf(body[pos:], match), "created")
+			if name == "" {
+				name = submatch(builderCatalogExtractor, stringsOf(body[pos:], match), "named")
+			}
+			brace := open + 1 + pos + match[1] - 1
+			catalogBody := blockBody(stripped, brace)
+			catalogs = append(catalogs, BuilderCatalog{
+				Name:        name,
+				declaration: [2]int{open + 1 + pos + match[0], brace + len(catalogBody) + 2},
+				body:        [2]int{brace + 1, brace + 1 + len(catalogBody)},
+			})
+			pos += match[1] + len(catalogBody) + 1
+		}
+	}
+	return catalogs
+}
+
+// stringsOf turns the indexes of a submatch into the strings they delimit in text.
+func stringsOf(text string, match []int) []string {
+	groups := make([]string, len(match)/2)
+	for i := range groups {
+		if match[2*i] >= 0 {
+			groups[i] = text[match[2*i]:match[2*i+1]]
+		}
+	}
+	return groups
+}
+
+// callArguments splits the arguments of the call whose parenthesis opens at open in text,
+// and returns where the call ends.
+func callArguments(text string, open int) ([]string, int) {
+	depth := 0
+	var quote byte
+	for i := open; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+			if depth == 0 {
+				return splitArguments(text[open+1 : i]), i + 1
+			}
+		}
+	}
+	return nil, len(text)
+}
+
+// splitArguments splits arguments at the commas outside string literals, calls and lists.
+func splitArguments(arguments string) []string {
+	split := make([]string, 0)
+	if strings.TrimSpace(arguments) == "" {
+		return split
+	}
+	depth := 0
+	var quote byte
+	last := 0
+	for i := 0; i < len(arguments); i++ {
+		c := arguments[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			split = append(split, strings.TrimSpace(arguments[last:i]))
+			last = i + 1
+		}
+	}
+	return append(split, strings.TrimSpace(arguments[last:]))
+}
+
+// catalogBuilder evaluates the arguments of builder calls with the variables the settings file sees.
+type catalogBuilder struct {
+	path    string
+	symbols *SymbolTable
+}
+
+// value evaluates a string literal, interpolating variables, or a variable.
+func (b catalogBuilder) value(argument string) (string, error) {
+	if literal, ok := unquote(argument); ok {
+		if !strings.HasPrefix(argument, `"`) || !strings.Contains(literal, "$") {
+			return literal, nil
+		}
+		resolution, ok, err := b.symbols.evaluate(VersionSymbol{Value: literal, Template: true, Path: b.path}, 0)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", fmt.Errorf("cannot resolve %s", argument)
+		}
+		return resolution.Value, nil
+	}
+	if identifierExtractor.MatchString(argument) {
+		resolution, ok, err := b.symbols.resolve(b.path, argument)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", fmt.Errorf("cannot resolve %s", argument)
+		}
+		return resolution.Value, nil
+	}
+	return "", fmt.Errorf("unsupported argument %s", argument)
+}
+
+// values evaluates the arguments, which must be count.
+func (b catalogBuilder) values(arguments []string, count int) ([]string, error) {
+	if len(arguments) != count {
+		return nil, fmt.Errorf("expected %d arguments, got %d", count, len(arguments))
+	}
+	values := make([]string, count)
+	for i, argument := range arguments {
+		value, err := b.value(argument)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// list evaluates a list, listOf("a", "b") in Kotlin or ['a', 'b'] in Groovy.
+func (b catalogBuilder) list(argument string) ([]string, error) {
+	if inner, ok := strings.CutPrefix(argument, "listOf("); ok && strings.HasSuffix(inner, ")") {
+		argument = strings.TrimSuffix(inner, ")")
+	} else if strings.HasPrefix(argument, "[") && strings.HasSuffix(argument, "]") {
+		argument = argument[1 : len(argument)-1]
+	} else {
+		return nil, fmt.Errorf("unsupported list %s", argument)
+	}
+	arguments := splitArguments(argument)
+	return b.values(arguments, len(arguments))
+}
+
+// chainedVersion reads the version a library or plugin declaration chains at pos in body, e.g. .version("1.0"),
+// .versionRef("kotlin"), .withoutVersion() or .version { strictly("1.0") }, and returns where the chain ends.
+func (b catalogBuilder) chainedVersion(body string, pos int) (any, int, error) {
+	match := builderChainExtractor.FindStringSubmatchIndex(body[pos:])
+	if match == nil {
+		return nil, pos, fmt.Errorf("no version declared")
+	}
+	method := body[pos+match[2] : pos+match[3]]
+	open := pos + match[4]
+	if body[open] == '{' {
+		block := blockBody(body, open)
+		return parseVersionBlock(block), open + len(block) + 2, nil
+	}
+	arguments, end := callArguments(body, open)
+	switch method {
+	case "withoutVersion":
+		return nil, end, nil
+	case "versionRef":
+		values, err := b.values(arguments, 1)
+		if err != nil {
+			return nil, end, err
+		}
+		return LooseLibrary{"ref": values[0]}, end, nil
+	}
+	values, err := b.values(arguments, 1)
+	if err != nil {
+		return nil, end, err
+	}
+	return values[0], end, nil
+}
+
+// parse builds the catalog declared by the builder calls of body.
+// Anything else in body fails, so that nothing is dropped from the settings file.
+func (b catalogBuilder) parse(body string, catalog *BuilderCatalog) (VersionCatalog, error) {
+	built := initVersionCatalog()
+	pos := 0
+	for pos < len(body) {
+		match := builderCallExtractor.FindStringSubmatchIndex(body[pos:])
+		if match == nil {
+			break
+		}
+		if skipped := body[pos : pos+match[0]]; !builderSeparatorExtractor.MatchString(skipped) {
+			return built, fmt.Errorf("unsupported statement %s", strings.TrimSpace(skipped))
+		}
+		call := body[pos+match[2] : pos+match[3]]
+		arguments, end := callArguments(body, pos+match[1]-1)
+		pos = end
+
+		switch call {
+		case "from":
+			if len(arguments) == 1 {
+				if files := builderFilesExtractor.FindStringSubmatch(arguments[0]); files != nil {
+					catalog.From = files[1]
+					continue
+				}
+			}
+			return built, fmt.Errorf("unsupported from(%s)", strings.Join(arguments, ", "))
+		case "version":
+			if following := strings.TrimLeft(body[pos:], " \t"); strings.HasPrefix(following, "{") {
+				open := len(body) - len(following)
+				block := blockBody(body, open)
+				pos = open + len(block) + 2
+				alias, err := b.values(arguments, 1)
+				if err != nil {
+					return built, err
+				}
+				rich := parseVersionBlock(block)
+				if require, ok := rich["require"]; ok && len(rich) == 1 {
+					built.Versions[alias[0]] = require
+				} else {
+					built.Versions[alias[0]] = rich
+				}
+				continue
+			}
+			values, err := b.values(arguments, 2)
+			if err != nil {
+				return built, err
+			}
+			built.Versions[values[0]] = values[1]
+		case "library":
+			values, err := b.values(arguments, len(arguments))
+			if err != nil {
+				return built, err
+			}
+			lib := make(LooseLibrary)
+			switch len(values) {
+			case 2:
+				parts := strings.Split(values[1], ":")
+				if len(parts) < 2 || len(parts) > 3 {
+					return built, fmt.Errorf("unsupported coordinates %s", values[1])
+				}
+				lib["group"], lib["name"] = parts[0], parts[1]
+				if len(parts) == 3 {
+					lib["version"] = parts[2]
+					built.Libraries[values[0]] = lib
+					continue
+				}
+			case 3:
+				lib["group"], lib["name"] = values[1], values[2]
+			default:
+				return built, fmt.Errorf("expected 2 or 3 arguments, got %d", len(values))
+			}
+			version, end, err := b.chainedVersion(body, pos)
+			if err != nil {
+				return built, err
+			}
+			pos = end
+			if version != nil {
+				lib["version"] = version
+			}
+			built.Libraries[values[0]] = lib
+		case "plugin":
+			values, err := b.values(arguments, 2)
+			if err != nil {
+				return built, err
+			}
+			version, end, err := b.chainedVersion(body, pos)
+			if err != nil {
+				return built, err
+			}
+			pos = end
+			built.Plugins[values[0]] = Plugin{Id: values[1], Version: version}
+		case "bundle":
+			if len(arguments) != 2 {
+				return built, fmt.Errorf("expected 2 arguments, got %d", len(arguments))
+			}
+			alias, err := b.value(arguments[0])
+			if err != nil {
+				return built, err
+			}
+			members, err := b.list(arguments[1])
+			if err != nil {
+				return built, err
+			}
+			built.Bundles[alias] = members
+		}
+	}
+	if rest := body[pos:]; !builderSeparatorExtractor.MatchString(rest) {
+		return built, fmt.Errorf("unsupported statement %s", strings.TrimSpace(rest))
+	}
+	return built, nil
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const defaultCatalogName = "libs"
+
+var catalogNamePattern = regexp.MustCompile(`^[a-z][a-zA-Z0-9]+$`)
+
+// CatalogLayout is where the catalogs of a build live according to its root settings file.
+type CatalogLayout struct {
+	Root string
+	// DefaultName is the name of the catalog Gradle creates from gradle/libs.versions.toml.
+	DefaultName string
+	// Declared maps the catalogs declared in versionCatalogs { ... } to their files.
+	Declared map[string]string
+}
+
+func readCatalogLayout(root string) (CatalogLayout, error) {
+	layout := CatalogLayout{Root: root, DefaultName: defaultCatalogName, Declared: make(map[string]string)}
+	settings, err := readSettings(root)
+	if err != nil || settings == nil {
+		return layout, err
+	}
+	layout.DefaultName = settings.DefaultLibrariesExtensionName
+	layout.Declared = settings.Catalogs
+	return layout, nil
+}
+
+func (l CatalogLayout) conventionalPath() string {
+	return filepath.Join(l.Root, "gradle", "libs.versions.toml")
+}
+
+// path returns the file of the catalog of the given name, declared or conventional.
+func (l CatalogLayout) path(name string) string {
+	if path, ok := l.Declared[name]; ok {
+		return path
+	}
+	if name == l.DefaultName {
+		return l.conventionalPath()
+	}
+	return filepath.Join(l.Root, "gradle", name+".versions.toml")
+}
+
+// isRegistered tells whether Gradle already knows the catalog at path under name.
+func (l CatalogLayout) isRegistered(name string, path string) bool {
+	if declared, ok := l.Declared[name]; ok {
+		return declared == path
+	}
+	return name == l.DefaultName && path == l.conventionalPath()
+}
+
+// existing returns the catalogs of the build whose files exist.
+func (l CatalogLayout) existing() map[string]string {
+	catalogs := make(map[string]string)
+	for name, path := range l.Declared {
+		if _, err := os.Stat(path); err == nil {
+			catalogs[name] = path
+		}
+	}
+	if _, ok := catalogs[l.DefaultName]; !ok {
+		if _, err := os.Stat(l.conventionalPath()); err == nil {
+			catalogs[l.DefaultName] = l.conventionalPath()
+		}
+	}
+	return catalogs
+}
+
+// catalogFlags reads --catalog-name and --catalog-path, resolving the path against root.
+// Without them the default catalog configured in the settings file is targeted.
+func catalogFlags(cmd *cobra.Command, layout CatalogLayout) (string, string, error) {
+	name, err := cmd.Flags().GetString("catalog-name")
+	if err != nil {
+		return "", "", fmt.Errorf("error option: %w", err)
+	}
+	if !cmd.Flags().Changed("catalog-name") {
+		name = layout.DefaultName
+	}
+	if !catalogNamePattern.MatchString(name) {
+		return "", "", fmt.Errorf("error option: invalid catalog name %q, must be a camelCase identifier like libs or testLibs", name)
+	}
+	path, err := cmd.Flags().GetString("catalog-path")
+	if err != nil {
+		return "", "", fmt.Errorf("error option: %w", err)
+	}
+	if path == "" {
+		return name, layout.path(name), nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(layout.Root, path)
+	}
+	return name, path, nil
+}
+
+// writeCatalogs stages the catalogs for writing. A catalog other than the one at defaultPath
+// keeps the entries it already has on disk.
+func writeCatalogs(catalogs map[string]VersionCatalog, paths map[string]string, defaultPath string, changes *ChangeSet) error {
+	for _, name := range slices.Sorted(maps.Keys(catalogs)) {
+		catalog := catalogs[name]
+		if paths[name] != defaultPath {
+			existing, err := ReadCatalog(paths[name])
+			if err != nil {
+				return fmt.Errorf("failed to read the existing %s: %w", paths[name], err)
+			}
+			catalog = mergeSplitCatalog(*existing, catalog)
+		}
+		changes.write(paths[name], formatCatalog(catalog))
+	}
+	return nil
+}
+
+// registerCatalogs adds the catalogs Gradle does not know yet to the root settings file,
+// and all of them to the settings files of the given included builds, in the dialect given for each.
+func registerCatalogs(layout CatalogLayout, paths map[string]string, builds map[string]Dialect, changes *ChangeSet) error {
+	root := layout.Root
+	fallback := Groovy
+	if _, err := os.Stat(filepath.Join(root, "build.gradle.kts")); err == nil {
+		fallback = Kotlin
+	}
+	rootSettingsPath := settingsFilePath(root, fallback)
+	files := make([]string, 0, len(paths))
+	for _, name := range slices.Sorted(maps.Keys(paths)) {
+		files = append(files, filepath.Base(paths[name]))
+	}
+	for _, build := range slices.Sorted(maps.Keys(builds)) {
+		fullPath := settingsFilePath(build, builds[build])
+		updated, err := writeCatalogSettings(fullPath, paths, changes)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", fullPath, err)
+		}
+		if updated {
+			fmt.Printf("Updated: %s%s", fullPath, LineBreak)
+			fmt.Printf("         ^ This file is used to resolve Version Catalog (%s) in %s.%s", strings.Join(files, ", "), filepath.Base(build), LineBreak)
+		}
+	}
+
+	unregistered := make(map[string]string)
+	for name, path := range paths {
+		if !layout.isRegistered(name, path) {
+			unregistered[name] = path
+		}
+	}
+	updated, err := writeCatalogSettings(rootSettingsPath, unregistered, changes)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", rootSettingsPath, err)
+	}
+	if updated {
+		fmt.Printf("Updated: %s%s", rootSettingsPath, LineBreak)
+	}
+	return nil
+}
+
+// buildsToWire maps the included builds holding any of the rewritten files
+// to the dialect of those files, which is used when the build has no settings file yet.
+func buildsToWire(root string, rewrittenFiles []string) (map[string]Dialect, error) {
+	builds, err := findIncludedBuilds(root)
+	if err != nil {
+		return nil, err
+	}
+	wired := make(map[string]Dialect)
+	for _, file := range rewrittenFiles {
+		if build, ok := owningBuild(builds, file); ok {
+			if _, ok := wired[build]; !ok {
+				wired[build] = dialectOf(file)
+			}
+		}
+	}
+	return wired, nil
+}
+
+// owningBuild returns the innermost of builds containing file.
+func owningBuild(builds []string, file string) (string, bool) {
+	owner := ""
+	for _, build := range builds {
+		relative, err := filepath.Rel(build, file)
+		if err != nil || strings.HasPrefix(relative, "..") {
+			continue
+		}
+		if len(build) > len(owner) {
+			owner = build
+		}
+	}
+	return owner, owner != ""
+}
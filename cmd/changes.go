@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// ChangeSet holds the file contents a run is going to write, so that nothing is touched
+// on disk until every rewrite has been computed and the repository state has been checked.
+type ChangeSet struct {
+	contents map[string]string
+	removed  map[string]bool
+}
+
+func newChangeSet() *ChangeSet {
+	return &ChangeSet{contents: make(map[string]string), removed: make(map[string]bool)}
+}
+
+// read returns the pending content of path, or the content on disk if it has not been changed.
+func (c *ChangeSet) read(path string) (string, error) {
+	if c.removed[path] {
+		return "", &fs.PathError{Op: "read", Path: path, Err: fs.ErrNotExist}
+	}
+	if content, ok := c.contents[path]; ok {
+		return content, nil
+	}
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+// write stages content for path. Content identical to what is on disk is not staged.
+func (c *ChangeSet) write(path string, content string) {
+	delete(c.removed, path)
+	if _, ok := c.contents[path]; !ok {
+		if bytes, err := os.ReadFile(path); err == nil && string(bytes) == content {
+			return
+		}
+	}
+	c.contents[path] = content
+}
+
+// remove stages the deletion of path.
+func (c *ChangeSet) remove(path string) {
+	delete(c.contents, path)
+	c.removed[path] = true
+}
+
+func (c *ChangeSet) paths() []string {
+	paths := make([]string, 0, len(c.contents)+len(c.removed))
+	for path := range c.contents {
+		paths = append(paths, path)
+	}
+	for path := range c.removed {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// apply writes the pending contents to disk and deletes the removed files.
+func (c *ChangeSet) apply() error {
+	for _, path := range c.paths() {
+		if c.removed[path] {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(c.contents[path]), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
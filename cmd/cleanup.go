@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var accessorSeparators = strings.NewReplacer("-", ".", "_", ".")
+
+// versionAccessor returns the expression reading a [versions] entry from the catalog.
+func versionAccessor(catalog string, key string) string {
+	return fmt.Sprintf("%s.versions.%s.get()", catalog, accessorSeparators.Replace(key))
+}
+
+// compileVariableDefinitionExtractor matches the definitions of a variable with a string literal,
+// e.g. val x = "1.0", ext.x = '1.0', extra["x"] = "1.0", extra.set("x", "1.0") or val x by extra("1.0")
+func compileVariableDefinitionExtractor(name string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(name)
+	heads := []string{
+		`(?:(?:private|internal|const)\s+)*(?:(?:val|var|def|String)\s+)?` + quoted + `(?:\s*:\s*String\??)?\s*=\s*`,
+		`(?:val|var)\s+` + quoted + `(?:\s*:\s*String\??)?\s+by\s+(?:(?:project|rootProject)\.)?extra\(\s*`,
+		`(?:(?:project|rootProject)\.)?ext\.` + quoted + `\s*=\s*`,
+		`(?:(?:project|rootProject)\.)?extra\[\s*"` + quoted + `"\s*]\s*=\s*`,
+		`(?:(?:project|rootProject)\.)?ext(?:ra)?\.set\(\s*["']` + quoted + `["']\s*,\s*`,
+	}
+	return regexp.MustCompile(`(?m)^(?P<head>[ \t]*(?:` + strings.Join(heads, "|") + `))["'][^"'\r\n]*["'](?P<tail>[ \t]*\)?[ \t]*;?[ \t]*(?:\r?\n)?)`)
+}
+
+func compileReferenceExtractor(name string) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`\b%s\b`, regexp.QuoteMeta(name)))
+}
+
+// cleanUpVersionVariables deletes the consumed version variables that are no longer referenced
+// after the rewrite. Build script variables still in use are redirected to the catalog instead,
+// so that the catalog is the single source of truth.
+// References are counted in readers too, the other files that may read properties, e.g. settings files.
+// The build script variables a consumed template interpolates, e.g. major and minor of val v = "$major.$minor",
+// are deleted too once nothing else references them, as are the variables whose value lost a version conflict.
+func cleanUpVersionVariables(buildFilePaths []string, readers []string, variables []VersionVariable, aliases Aliases, style PrecompiledStyle, changes *ChangeSet) error {
+	contents := make(map[string]string)
+	for _, path := range buildFilePaths {
+		content, err := changes.read(path)
+		if err != nil {
+			return err
+		}
+		contents[path] = content
+	}
+	readerContents := make([]string, 0)
+	for _, path := range readers {
+		if _, isBuildFile := contents[path]; isBuildFile {
+			continue
+		}
+		content, err := changes.read(path)
+		if err != nil {
+			return err
+		}
+		readerContents = append(readerContents, content)
+	}
+
+	definedIn := make(map[string][]string)
+	keys := make(map[[2]string]string)
+	names := make([]string, 0)
+	for _, variable := range variables {
+		keys[[2]string{variable.Name, variable.Path}] = variable.Key
+		if _, ok := definedIn[variable.Name]; !ok {
+			names = append(names, variable.Name)
+		}
+		if !slices.Contains(definedIn[variable.Name], variable.Path) {
+			definedIn[variable.Name] = append(definedIn[variable.Name], variable.Path)
+		}
+	}
+
+	// interpolated are the variables queued as referenced by the templates rewritten
+	interpolated := make(map[string]bool)
+	queueInterpolated := func(definitionExtractor *regexp.Regexp, content string) {
+		for _, match := range definitionExtractor.FindAllStringSubmatchIndex(content, -1) {
+			// the literal lies between the head and the tail
+			literal := content[match[3]:match[4]]
+			if !strings.HasPrefix(literal, `"`) {
+				continue
+			}
+			for _, reference := range templateReferenceExtractor.FindAllStringSubmatch(literal, -1) {
+				referenced := reference[1] + reference[2]
+				if identifierExtractor.MatchString(referenced) && !slices.Contains(names, referenced) {
+					names = append(names, referenced)
+					interpolated[referenced] = true
+				}
+			}
+		}
+	}
+
+	for i := 0; i < len(names); i++ {
+		name := names[i]
+		definitionExtractor := compileVariableDefinitionExtractor(name)
+		referenceExtractor := compileReferenceExtractor(name)
+		references := 0
+		for _, path := range buildFilePaths {
+			content := contents[path]
+			references += len(referenceExtractor.FindAllStringIndex(content, -1))
+			references -= len(definitionExtractor.FindAllStringIndex(content, -1))
+		}
+		for _, content := range readerContents {
+			references += len(referenceExtractor.FindAllStringIndex(content, -1))
+		}
+
+		if interpolated[name] {
+			if references > 0 {
+				continue
+			}
+			for _, path := range buildFilePaths {
+				queueInterpolated(definitionExtractor, contents[path])
+				content := definitionExtractor.ReplaceAllString(contents[path], "")
+				if content != contents[path] {
+					contents[path] = content
+					changes.write(path, content)
+					fmt.Printf("Removed: %s from %s%s", name, path, LineBreak)
+				}
+			}
+			continue
+		}
+
+		for _, path := range definedIn[name] {
+			if _, isBuildFile := contents[path]; !isBuildFile {
+				if references > 0 {
+					fmt.Printf("NOTICE: %s in %s is still referenced, keeping it.%s", name, path, LineBreak)
+					continue
+				}
+				content, err := changes.read(path)
+				if err != nil {
+					return err
+				}
+				changes.write(path, removeProperty(content, name))
+				fmt.Printf("Removed: %s from %s%s", name, path, LineBreak)
+				continue
+			}
+
+			content := contents[path]
+			key := keys[[2]string{name, path}]
+			if key == "" {
+				// the value is not in the catalog, so the variable stays as long as it is in use
+				if references > 0 {
+					continue
+				}
+				queueInterpolated(definitionExtractor, content)
+				content = definitionExtractor.ReplaceAllString(content, "")
+				if content != contents[path] {
+					contents[path] = content
+					changes.write(path, content)
+					fmt.Printf("Removed: %s from %s%s", name, path, LineBreak)
+				}
+				continue
+			}
+			accessor := versionAccessor(aliases.versionCatalog(key), key)
+			if usesCatalogAPI(path, style) {
+				accessor = catalogAPIVersion(dialectOf(path), aliases.versionCatalog(key), key)
+			}
+			queueInterpolated(definitionExtractor, content)
+			if references > 0 {
+				replacement := fmt.Sprintf("${head}%s${tail}", accessor)
+				content = definitionExtractor.ReplaceAllString(content, replacement)
+			} else {
+				content = definitionExtractor.ReplaceAllString(content, "")
+			}
+			if content != contents[path] {
+				contents[path] = content
+				changes.write(path, content)
+				if references > 0 {
+					fmt.Printf("Replaced: %s in %s with %s%s", name, path, accessor, LineBreak)
+				} else {
+					fmt.Printf("Removed: %s from %s%s", name, path, LineBreak)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ConflictStrategy decides which version wins when a library is declared with different versions.
+type ConflictStrategy string
+
+const (
+	ConflictHighest  ConflictStrategy = "highest"
+	ConflictLowest   ConflictStrategy = "lowest"
+	ConflictFail     ConflictStrategy = "fail"
+	ConflictKeepBoth ConflictStrategy = "keep-both"
+)
+
+var conflictStrategies = []ConflictStrategy{ConflictHighest, ConflictLowest, ConflictFail, ConflictKeepBoth}
+
+func parseConflictStrategy(value string) (ConflictStrategy, error) {
+	strategy := ConflictStrategy(value)
+	if !slices.Contains(conflictStrategies, strategy) {
+		return "", fmt.Errorf("unknown conflict strategy %q, must be one of %v", value, conflictStrategies)
+	}
+	return strategy, nil
+}
+
+// VersionConflict is a library declared with different versions across build files.
+type VersionConflict struct {
+	Coordinate string
+	Usages     []StrictLibrary
+	Resolution string
+}
+
+func (c VersionConflict) String() string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%s (%s)", c.Coordinate, c.Resolution))
+	for _, usage := range c.Usages {
+		builder.WriteString(LineBreak)
+		builder.WriteString(fmt.Sprintf("      %s in %s", usage.Version, usage.Path))
+	}
+	return builder.String()
+}
+
+// resolveConflict picks the libraries to be cataloged among usages of the same coordinate,
+// each paired with the alias suffix it should be cataloged under.
+// versionOf returns the comparable version of a usage, or "" when it has no version yet.
+func resolveConflict(coordinate string, usages []StrictLibrary, versionOf func(StrictLibrary) string, strategy ConflictStrategy) (map[string]StrictLibrary, *VersionConflict) {
+	distinct := make([]string, 0)
+	for _, usage := range usages {
+		if v := versionOf(usage); v != "" && !slices.Contains(distinct, v) {
+			distinct = append(distinct, v)
+		}
+	}
+	// pick prefers a usage reading a variable, so that the [versions] entry of the variable stays in use
+	pick := func(version string) StrictLibrary {
+		index := slices.IndexFunc(usages, func(usage StrictLibrary) bool {
+			return versionOf(usage) == version && strings.HasPrefix(usage.Version, "$")
+		})
+		if index < 0 {
+			index = slices.IndexFunc(usages, func(usage StrictLibrary) bool {
+				return versionOf(usage) == version
+			})
+		}
+		return usages[index]
+	}
+	if len(distinct) == 0 {
+		return map[string]StrictLibrary{"": usages[0]}, nil
+	}
+	if len(distinct) == 1 {
+		return map[string]StrictLibrary{"": pick(distinct[0])}, nil
+	}
+
+	slices.SortFunc(distinct, func(a, b string) int {
+		return compareVersions(b, a)
+	})
+	conflict := &VersionConflict{Coordinate: coordinate}
+	for _, usage := range usages {
+		if version := versionOf(usage); version != "" {
+			usage.Version = version
+			conflict.Usages = append(conflict.Usages, usage)
+		}
+	}
+
+	switch strategy {
+	case ConflictLowest:
+		lowest := distinct[len(distinct)-1]
+		conflict.Resolution = "lowest: " + lowest
+		return map[string]StrictLibrary{"": pick(lowest)}, conflict
+	case ConflictKeepBoth:
+		chosen := map[string]StrictLibrary{"": pick(distinct[0])}
+		for _, version := range distinct[1:] {
+			chosen[versionSuffix(version)] = pick(version)
+		}
+		conflict.Resolution = "kept all: " + strings.Join(distinct, ", ")
+		return chosen, conflict
+	case ConflictFail:
+		conflict.Resolution = "versions: " + strings.Join(distinct, ", ")
+		return nil, conflict
+	default:
+		conflict.Resolution = "highest: " + distinct[0]
+		return map[string]StrictLibrary{"": pick(distinct[0])}, conflict
+	}
+}
+
+// versionSuffix makes an alias suffix out of a version, e.g. 31.1-jre -> -v311-jre
+func versionSuffix(version string) string {
+	return "-" + safeKey("v"+version)
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var convertCommand = &cobra.Command{
+	Use:   "convert [PATH]",
+	Short: "Convert catalogs declared in the settings file into TOML catalogs",
+	Long: `
+Moves the catalogs declared with version(), library(), bundle() and plugin() calls in versionCatalogs { ... }
+of the settings file (PATH/settings.gradle(.kts)) into TOML files, and imports them with from(files(...)) instead.
+The aliases are kept, so build files need no change.
+If no PATH is provided, the current working directory is used.
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 1 {
+			return errors.New("requires at most one arg")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		gradleProjectRootPath, err := getWorkingDirectory(args)
+		if err != nil {
+			return err
+		}
+		allowDirty, err := cmd.Flags().GetBool("allow-dirty")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+
+		settingsPath := settingsFilePath(gradleProjectRootPath, Kotlin)
+		if _, err := os.Stat(settingsPath); err != nil {
+			return fmt.Errorf("no settings file to convert: %w", err)
+		}
+		layout, err := readCatalogLayout(gradleProjectRootPath)
+		if err != nil {
+			return fmt.Errorf("failed to read the settings file: %w", err)
+		}
+		symbols, err := newSymbolTable([]string{settingsPath}, newPropertyFiles(gradleProjectRootPath, nil))
+		if err != nil {
+			return fmt.Errorf("failed to read the settings file: %w", err)
+		}
+
+		changes := newChangeSet()
+		content, err := changes.read(settingsPath)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", settingsPath, err)
+		}
+		builder := catalogBuilder{path: settingsPath, symbols: symbols}
+		catalogPaths := make([]string, 0)
+		updated := content
+		// the declarations are parsed without their comments, whose offsets are kept
+		stripped := stripComments(content)
+		declarations := findBuilderCatalogs(content)
+		// back to front, so that the offsets stay valid
+		for i := len(declarations) - 1; i >= 0; i-- {
+			declaration := declarations[i]
+			built, err := builder.parse(stripped[declaration.body[0]:declaration.body[1]], &declaration)
+			if err != nil {
+				fmt.Printf("NOTICE: The catalog %s in %s is left as it is: %s%s", declaration.Name, settingsPath, err, LineBreak)
+				continue
+			}
+			if len(built.Versions)+len(built.Libraries)+len(built.Plugins)+len(built.Bundles) == 0 {
+				continue
+			}
+			catalogPath := layout.path(declaration.Name)
+			if declaration.From != "" {
+				catalogPath = resolveSettingsPath(gradleProjectRootPath, declaration.From)
+			}
+			existing, err := ReadCatalog(catalogPath)
+			if err != nil {
+				return fmt.Errorf("failed to read the existing %s: %w", catalogPath, err)
+			}
+			changes.write(catalogPath, formatCatalog(mergeSplitCatalog(*existing, built)))
+			catalogPaths = append(catalogPaths, catalogPath)
+
+			updated, err = replaceBuilderCatalog(updated, settingsPath, declaration, catalogPath, layout)
+			if err != nil {
+				return err
+			}
+		}
+		if len(catalogPaths) == 0 {
+			fmt.Printf("NOTICE: No catalog to convert in %s.%s", settingsPath, LineBreak)
+			return nil
+		}
+		changes.write(settingsPath, updated)
+
+		workTree, err := findGitWorkTree(gradleProjectRootPath)
+		if err != nil {
+			return fmt.Errorf("failed to detect a git work tree: %w", err)
+		}
+		workTree, err = inspectableWorkTree(workTree, allowDirty)
+		if err != nil {
+			return err
+		}
+		if workTree != nil && !allowDirty {
+			err = workTree.ensureClean(changes.paths())
+			if err != nil {
+				return err
+			}
+		}
+		err = changes.apply()
+		if err != nil {
+			return fmt.Errorf("failed to write files: %w", err)
+		}
+		for i := len(catalogPaths) - 1; i >= 0; i-- {
+			fmt.Printf("Generated: %s%s", catalogPaths[i], LineBreak)
+		}
+		fmt.Printf("Updated: %s%s", settingsPath, LineBreak)
+		return nil
+	},
+}
+
+// replaceBuilderCatalog makes the declaration import the catalog at catalogPath.
+// The default catalog in gradle/libs.versions.toml is imported by Gradle itself, so its declaration is removed.
+func replaceBuilderCatalog(content string, settingsPath string, declaration BuilderCatalog, catalogPath string, layout CatalogLayout) (string, error) {
+	lineStart := strings.LastIndexByte(content[:declaration.declaration[0]], '\n') + 1
+	indent := content[lineStart:declaration.declaration[0]]
+	if declaration.Name == layout.DefaultName && catalogPath == layout.conventionalPath() && strings.TrimSpace(indent) == "" {
+		end := declaration.declaration[1]
+		if rest := content[end:]; strings.HasPrefix(rest, "\r\n") {
+			end += 2
+		} else if strings.HasPrefix(rest, "\n") {
+			end++
+		}
+		return content[:lineStart] + content[end:], nil
+	}
+
+	relativePath, err := filepath.Rel(filepath.Dir(settingsPath), catalogPath)
+	if err != nil {
+		return "", err
+	}
+	from := fmt.Sprintf(`from(files("%s"))`, filepath.ToSlash(relativePath))
+	if dialectOf(settingsPath) == Groovy {
+		from = fmt.Sprintf(`from(files('%s'))`, filepath.ToSlash(relativePath))
+	}
+	return content[:declaration.body[0]] + LineBreak + indent + "    " + from + LineBreak + indent + content[declaration.body[1]:], nil
+}
+
+func init() {
+	rootCmd.AddCommand(convertCommand)
+	convertCommand.Flags().Bool("allow-dirty", false, "Rewrite files even if they have uncommitted changes in git")
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ManagedVersion is a version declared in a dependencyManagement { } block of the Spring dependency management plugin,
+// with the modules it applies to.
+type ManagedVersion struct {
+	// Name is what the [versions] key is derived from: the module, or the group of a dependencySet
+	Name      string
+	Value     string
+	Libraries []StrictLibrary
+	// literal delimits the string literal holding the version, and prefix is what it holds before the version, e.g. g:a:
+	literal [2]int
+	prefix  string
+}
+
+var dependencyManagementExtractor = regexp.MustCompile(`\bdependencyManagement\s*\{`)
+
+// mavenBom("g:a:1.0") and dependency("g:a:1.0")
+var managedModuleExtractor = regexp.MustCompile(`\b(?:mavenBom|dependency)\s*\(?\s*(?P<literal>["'](?P<group>[^:"'$\r\n]+):(?P<name>[^:"'$\r\n]+):(?P<version>[^:"'$\r\n]+)["'])`)
+
+// dependencySet(group: "g", version: "1.0") in Groovy and dependencySet("g:1.0") in Kotlin
+var dependencySetExtractor = regexp.MustCompile(`\bdependencySet\s*\(\s*(?:group\s*[:=]\s*["'](?P<group>[^"'\r\n]+)["']\s*,\s*version\s*[:=]\s*(?P<version>["'][^"'$\r\n]+["'])|(?P<literal>["'](?P<setGroup>[^:"'\r\n]+):(?P<setVersion>[^:"'$\r\n]+)["']))\s*\)`)
+var dependencySetEntryExtractor = regexp.MustCompile(`\bentry\s*\(?\s*["']([^"'\r\n]+)["']`)
+
+// findManagedVersions collects the versions of the dependencyManagement { } blocks in text.
+func findManagedVersions(text string) []ManagedVersion {
+	managed := make([]ManagedVersion, 0)
+	for _, location := range dependencyManagementExtractor.FindAllStringIndex(text, -1) {
+		open := location[1] - 1
+		body := blockBody(text, open)
+		offset := open + 1
+		group := func(re *regexp.Regexp, match []int, name string) (string, [2]int) {
+			index := 2 * re.SubexpIndex(name)
+			if match[index] < 0 {
+				return "", [2]int{-1, -1}
+			}
+			return body[match[index]:match[index+1]], [2]int{offset + match[index], offset + match[index+1]}
+		}
+
+		for _, match := range managedModuleExtractor.FindAllStringSubmatchIndex(body, -1) {
+			lib := StrictLibrary{}
+			lib.Group, _ = group(managedModuleExtractor, match, "group")
+			lib.Name, _ = group(managedModuleExtractor, match, "name")
+			lib.Version, _ = group(managedModuleExtractor, match, "version")
+			_, literal := group(managedModuleExtractor, match, "literal")
+			managed = append(managed, ManagedVersion{
+				Name:      catalogSafeKey(lib),
+				Value:     lib.Version,
+				Libraries: []StrictLibrary{lib},
+				literal:   literal,
+				prefix:    lib.Group + ":" + lib.Name + ":",
+			})
+		}
+
+		for _, match := range dependencySetExtractor.FindAllStringSubmatchIndex(body, -1) {
+			set := ManagedVersion{}
+			setGroup, _ := group(dependencySetExtractor, match, "setGroup")
+			if setGroup != "" {
+				set.Value, _ = group(dependencySetExtractor, match, "setVersion")
+				_, set.literal = group(dependencySetExtractor, match, "literal")
+				set.prefix = setGroup + ":"
+			} else {
+				var quoted string
+				setGroup, _ = group(dependencySetExtractor, match, "group")
+				quoted, set.literal = group(dependencySetExtractor, match, "version")
+				set.Value, _ = unquote(quoted)
+			}
+			set.Name = safeKey(setGroup)
+			if brace, ok := closureFollowing(body[match[1]:]); ok {
+				for _, entry := range dependencySetEntryExtractor.FindAllStringSubmatch(blockBody(body, match[1]+brace), -1) {
+					set.Libraries = append(set.Libraries, StrictLibrary{Group: setGroup, Name: entry[1], Version: set.Value})
+				}
+			}
+			managed = append(managed, set)
+		}
+	}
+	return managed
+}
+
+// rewriteDependencyManagement points the versions of dependencyManagement { } blocks to the catalog.
+// The plugin only takes string notations, so the version is interpolated, e.g. dependency("g:a:${libs.versions.g.a.get()}")
+func rewriteDependencyManagement(dialect Dialect, content string, path string, aliases Aliases, style PrecompiledStyle) string {
+	managed := findManagedVersions(content)
+	// back to front, so that the offsets stay valid
+	slices.SortFunc(managed, func(a, b ManagedVersion) int { return a.literal[0] - b.literal[0] })
+	for i := len(managed) - 1; i >= 0; i-- {
+		version := managed[i]
+		key, ok := aliases.versionKeys[[2]string{version.Name, version.Value}]
+		if !ok {
+			continue
+		}
+		accessor := versionAccessor(aliases.versionCatalog(key), key)
+		if usesCatalogAPI(path, style) {
+			accessor = catalogAPIVersion(dialect, aliases.versionCatalog(key), key)
+		}
+		if version.prefix != "" {
+			accessor = `"` + version.prefix + "${" + strings.TrimSpace(accessor) + `}"`
+		}
+		content = content[:version.literal[0]] + accessor + content[version.literal[1]:]
+	}
+	return content
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dialect is the DSL a Gradle script is written in.
+type Dialect int
+
+const (
+	Groovy Dialect = iota
+	Kotlin
+)
+
+func dialectOf(path string) Dialect {
+	if strings.HasSuffix(path, ".kts") {
+		return Kotlin
+	}
+	return Groovy
+}
+
+func (d Dialect) settingsFileName() string {
+	if d == Kotlin {
+		return "settings.gradle.kts"
+	}
+	return "settings.gradle"
+}
+
+// quote wraps s in the quote char used by the original declaration.
+// Kotlin only has double-quoted strings, so the original style is ignored there.
+func (d Dialect) quote(s string, original string) string {
+	if d == Groovy && original == "'" {
+		return "'" + s + "'"
+	}
+	return `"` + s + `"`
+}
+
+// call renders `name(arg)` keeping the parentheses and spacing of the original call.
+// A paren-less Groovy call like `implementation "foo:bar"` stays paren-less.
+func (d Dialect) call(name, open, arg, close string) string {
+	if strings.Contains(open, "(") {
+		return name + open + arg + close
+	}
+	if d == Kotlin {
+		return fmt.Sprintf("%s(%s)%s", name, arg, close)
+	}
+	if open == "" {
+		open = " "
+	}
+	return name + open + arg + close
+}
+
+// settingsFilePath returns the settings file of the build in dir.
+// An existing settings file decides the dialect; otherwise fallback is used.
+func settingsFilePath(dir string, fallback Dialect) string {
+	for _, d := range []Dialect{Kotlin, Groovy} {
+		path := filepath.Join(dir, d.settingsFileName())
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(dir, fallback.settingsFileName())
+}
@@ -0,0 +1,333 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Directories that never contain build scripts of the project itself.
+var skippedDirectories = []string{"build", ".gradle", ".git", ".idea", "node_modules"}
+
+func isSkippedDirectory(path string) bool {
+	name := filepath.Base(path)
+	if slices.Contains(skippedDirectories, name) {
+		return true
+	}
+	// src/test/resources and the like hold test fixtures, not build scripts
+	return name == "resources" && filepath.Base(filepath.Dir(filepath.Dir(path))) == "src"
+}
+
+func isBuildScript(name string) bool {
+	return strings.HasSuffix(name, ".gradle") || strings.HasSuffix(name, ".gradle.kts")
+}
+
+// PathFilter selects discovered files by glob patterns relative to the project root.
+type PathFilter struct {
+	Include []string
+	Exclude []string
+}
+
+func (f PathFilter) matches(root string, path string) (bool, error) {
+	relative, err := filepath.Rel(root, path)
+	if err != nil {
+		return false, err
+	}
+	relative = filepath.ToSlash(relative)
+	if len(f.Include) > 0 {
+		included, err := matchesAnyGlob(f.Include, relative)
+		if err != nil || !included {
+			return false, err
+		}
+	}
+	excluded, err := matchesAnyGlob(f.Exclude, relative)
+	return !excluded, err
+}
+
+func matchesAnyGlob(patterns []string, path string) (bool, error) {
+	for _, pattern := range patterns {
+		re, err := compileGlob(pattern)
+		if err != nil {
+			return false, err
+		}
+		if re.MatchString(path) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// compileGlob supports `*` and `?` within a path segment and `**` across segments.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			builder.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			builder.WriteString(".*")
+			i++
+		case c == '*':
+			builder.WriteString("[^/]*")
+		case c == '?':
+			builder.WriteString("[^/]")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	builder.WriteString("$")
+	re, err := regexp.Compile(builder.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// discoverBuildFiles lists the build scripts of the project in root.
+// Modules are taken from the include(...) statements in the root settings file,
+// and the directory walk limited by maxDepth is used only when there are none.
+// Files ignored by git are left out when workTree is not nil.
+func discoverBuildFiles(root string, maxDepth int, filter PathFilter, workTree *GitWorkTree) ([]string, error) {
+	files, err := discoverBuildFilesFromSettings(root)
+	if err != nil {
+		return nil, err
+	}
+	if files == nil {
+		files, err = findBuildGradle(root, maxDepth, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	scripts, err := findPrecompiledScripts(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, script := range scripts {
+		if !slices.Contains(files, script) {
+			files = append(files, script)
+		}
+	}
+	ignored := make(map[string]bool)
+	if workTree != nil {
+		ignored, err = workTree.ignoredFiles(files)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	filtered := make([]string, 0, len(files))
+	for _, file := range files {
+		if ignored[file] {
+			fmt.Printf("NOTICE: skipping %s ignored by git%s", file, LineBreak)
+			continue
+		}
+		ok, err := filter.matches(root, file)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered, nil
+}
+
+// discoverBuildFilesFromSettings returns nil if the root settings file declares no modules.
+func discoverBuildFilesFromSettings(root string) ([]string, error) {
+	settings, err := readSettings(root)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil || len(settings.Projects) == 0 {
+		return nil, nil
+	}
+
+	directories := []string{root}
+	visitedBuilds := map[string]bool{root: true}
+	var addBuild func(settings *GradleSettings) error
+	addBuild = func(settings *GradleSettings) error {
+		for _, dir := range settings.Projects {
+			directories = append(directories, dir)
+		}
+		for _, dir := range settings.IncludedBuilds {
+			if visitedBuilds[dir] {
+				continue
+			}
+			visitedBuilds[dir] = true
+			directories = append(directories, dir)
+			included, err := readSettings(dir)
+			if err != nil {
+				return err
+			}
+			if included != nil {
+				if err := addBuild(included); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := addBuild(settings); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(root, "buildSrc")); err == nil {
+		directories = append(directories, filepath.Join(root, "buildSrc"))
+	}
+
+	files := make([]string, 0)
+	for _, dir := range directories {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			fmt.Printf("NOTICE: skipping %s: %v%s", dir, err, LineBreak)
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if entry.IsDir() || !isBuildScript(entry.Name()) || path == settings.Path || slices.Contains(files, path) {
+				continue
+			}
+			files = append(files, path)
+		}
+	}
+
+	scripts, err := findAppliedScripts(root, files)
+	if err != nil {
+		return nil, err
+	}
+	for _, script := range scripts {
+		if !slices.Contains(files, script) {
+			files = append(files, script)
+		}
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+// findIncludedBuilds lists the builds whose scripts can use the catalogs of the build in root:
+// its buildSrc and the builds included via includeBuild(...) at any depth, with their own buildSrc.
+func findIncludedBuilds(root string) ([]string, error) {
+	builds := make([]string, 0)
+	visited := map[string]bool{root: true}
+	var visit func(dir string) error
+	visit = func(dir string) error {
+		buildSrc := filepath.Join(dir, "buildSrc")
+		if info, err := os.Stat(buildSrc); err == nil && info.IsDir() && !visited[buildSrc] {
+			visited[buildSrc] = true
+			builds = append(builds, buildSrc)
+		}
+		settings, err := readSettings(dir)
+		if err != nil || settings == nil {
+			return err
+		}
+		for _, included := range settings.IncludedBuilds {
+			if visited[included] {
+				continue
+			}
+			visited[included] = true
+			builds = append(builds, included)
+			if err := visit(included); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit(root); err != nil {
+		return nil, err
+	}
+	return builds, nil
+}
+
+// findIncludedBuildSources lists the files accepted by name under the given source directories
+// of the builds included by the build in root.
+func findIncludedBuildSources(root string, directories []string, accept func(name string) bool) ([]string, error) {
+	builds, err := findIncludedBuilds(root)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]string, 0)
+	for _, build := range builds {
+		for _, dir := range directories {
+			err := filepath.WalkDir(filepath.Join(build, dir), func(path string, entry os.DirEntry, err error) error {
+				if os.IsNotExist(err) {
+					return filepath.SkipDir
+				}
+				if err != nil {
+					return err
+				}
+				if !entry.IsDir() && accept(entry.Name()) {
+					sources = append(sources, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sources, nil
+}
+
+// findPropertyReaders lists the files besides build scripts that may read the Gradle properties of the build in root:
+// the settings files of the build and of the builds it includes, and the Kotlin and Groovy sources of buildSrc and included builds.
+func findPropertyReaders(root string) ([]string, error) {
+	builds, err := findIncludedBuilds(root)
+	if err != nil {
+		return nil, err
+	}
+	readers := make([]string, 0)
+	for _, build := range append([]string{root}, builds...) {
+		for _, d := range []Dialect{Kotlin, Groovy} {
+			path := filepath.Join(build, d.settingsFileName())
+			if _, err := os.Stat(path); err == nil {
+				readers = append(readers, path)
+			}
+		}
+	}
+	sources, err := findIncludedBuildSources(root, precompiledScriptDirectories, func(name string) bool {
+		return isBuildScript(name) || strings.HasSuffix(name, ".kt") || strings.HasSuffix(name, ".groovy")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(readers, sources...), nil
+}
+
+var applyFromExtractor = regexp.MustCompile(`\bapply\s*\(?\s*from\s*[:=]\s*(?:file\(\s*)?["']([^"'\r\n]+)["']`)
+
+// findAppliedScripts lists local scripts applied with `apply from: "..."`, which live outside module directories.
+func findAppliedScripts(root string, files []string) ([]string, error) {
+	scripts := make([]string, 0)
+	for _, file := range files {
+		bytes, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range applyFromExtractor.FindAllStringSubmatch(stripComments(string(bytes)), -1) {
+			path, ok := resolveAppliedScript(root, file, match[1])
+			if !ok {
+				continue
+			}
+			if _, err := os.Stat(path); err == nil && isBuildScript(path) {
+				scripts = append(scripts, path)
+			}
+		}
+	}
+	return scripts, nil
+}
+
+// resolveAppliedScript returns the local path of a script applied from file, unless it is remote.
+func resolveAppliedScript(root string, file string, script string) (string, bool) {
+	if strings.Contains(script, "://") {
+		return "", false
+	}
+	base := filepath.Dir(file)
+	if settingsDirVariables.MatchString(script) {
+		base = root
+	}
+	return resolveSettingsPath(base, script), true
+}
@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ext properties can be read through the project or the root project, e.g. rootProject.ext.deps.okhttp
+const extQualifierPattern = `(?:(?:rootProject|project)\.)?(?:ext\.)?`
+
+var extQualifierExtractor = regexp.MustCompile(`^` + extQualifierPattern)
+var extMapAssignmentExtractor = regexp.MustCompile(`(?m)^[ \t]*(?:(?:rootProject|project)\.)?ext\.(\w+)\s*=\s*\[`)
+var extBlockExtractor = regexp.MustCompile(`(?m)^[ \t]*(?:(?:rootProject|project)\.)?ext\s*\{`)
+var extBlockMapAssignmentExtractor = regexp.MustCompile(`(?m)^[ \t]*(\w+)\s*=\s*\[`)
+var emptyExtBlockExtractor = regexp.MustCompile(`(?m)^[ \t]*(?:(?:rootProject|project)\.)?ext\s*\{\s*}[ \t]*;?[ \t]*(?:\r?\n|$)`)
+var groovyMapKeyExtractor = regexp.MustCompile(`^(?:(\w+)|'([^'\r\n]*)'|"([^"\r\n]*)")\s*:\s*`)
+var statementEndExtractor = regexp.MustCompile(`^[ \t]*;?[ \t]*(?:\r?\n)?`)
+
+// parseExtMaps collects the entries of the maps a Groovy script assigns to ext properties,
+// e.g. ext.deps = [...], rootProject.ext.deps = [...] or deps = [...] in an ext { } block.
+func parseExtMaps(path string, content string) DependencyObjectFile {
+	file := DependencyObjectFile{Path: path, Dialect: Groovy, Objects: make([]string, 0), Members: make([]ObjectMember, 0)}
+	stripped := stripComments(content)
+	parseAssignment := func(location []int, offset int) {
+		name := stripped[offset+location[2] : offset+location[3]]
+		members := len(file.Members)
+		end := parseGroovyMap(content, stripped, offset+location[1]-1, name+".", &file)
+		if end < 0 {
+			// a list or anything else than a map literal
+			file.Members = file.Members[:members]
+			return
+		}
+		end += len(statementEndExtractor.FindString(stripped[end:]))
+		file.Objects = append(file.Objects, name)
+		file.declarations = append(file.declarations, [2]int{offset + location[0], end})
+	}
+	for _, location := range extMapAssignmentExtractor.FindAllStringSubmatchIndex(stripped, -1) {
+		parseAssignment(location, 0)
+	}
+	for _, location := range extBlockExtractor.FindAllStringIndex(stripped, -1) {
+		open := location[1] - 1
+		body := blockBody(stripped, open)
+		for _, assignment := range extBlockMapAssignmentExtractor.FindAllStringSubmatchIndex(body, -1) {
+			parseAssignment(assignment, open+1)
+		}
+	}
+	// keep the declarations in the order of the file, so that they can be cut out back to front
+	order := make([]int, len(file.Objects))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int { return file.declarations[a][0] - file.declarations[b][0] })
+	objects, declarations := make([]string, len(order)), make([][2]int, len(order))
+	for i, index := range order {
+		objects[i], declarations[i] = file.Objects[index], file.declarations[index]
+	}
+	file.Objects, file.declarations = objects, declarations
+	return file
+}
+
+// parseGroovyMap collects the entries of the map literal opening at open, prefixing their keys,
+// and returns where the literal ends, or -1 if it is not a map literal.
+// Entries whose value is not a string literal are collected without a value, so that they are never migrated.
+func parseGroovyMap(content string, stripped string, open int, prefix string, file *DependencyObjectFile) int {
+	i := open + 1
+	skipSeparators := func() {
+		for i < len(stripped) && strings.ContainsRune(" \t\r\n,", rune(stripped[i])) {
+			i++
+		}
+	}
+	skipSeparators()
+	if strings.HasPrefix(stripped[i:], ":") {
+		i++
+		skipSeparators()
+		if i < len(stripped) && stripped[i] == ']' {
+			return i + 1
+		}
+		return -1
+	}
+	for i < len(stripped) {
+		skipSeparators()
+		if i >= len(stripped) {
+			break
+		}
+		if stripped[i] == ']' {
+			return i + 1
+		}
+		location := groovyMapKeyExtractor.FindStringSubmatchIndex(stripped[i:])
+		if location == nil {
+			return -1
+		}
+		match := groovyMapKeyExtractor.FindStringSubmatch(stripped[i:])
+		key := match[1] + match[2] + match[3]
+		start := i
+		i += location[1]
+		if i >= len(stripped) {
+			break
+		}
+		switch stripped[i] {
+		case '[':
+			members := len(file.Members)
+			if end := parseGroovyMap(content, stripped, i, prefix+key+".", file); end >= 0 {
+				i = end
+				continue
+			}
+			file.Members = file.Members[:members]
+		case '"', '\'':
+			quote := stripped[i]
+			end := i + 1
+			for end < len(stripped) && stripped[end] != quote && stripped[end] != '\n' {
+				if stripped[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(stripped) && stripped[end] == quote {
+				value := content[i+1 : end]
+				if quote == '\'' && strings.Contains(value, "$") {
+					// single quoted strings are not interpolated
+					value = ""
+				}
+				file.Members = append(file.Members, ObjectMember{Reference: prefix + key, Value: value, offset: start, dialect: Groovy})
+				i = end + 1
+				continue
+			}
+		}
+		// other values, e.g. numbers, lists or expressions, stay out of the catalog
+		file.Members = append(file.Members, ObjectMember{Reference: prefix + key, offset: start, dialect: Groovy})
+		i = skipGroovyValue(stripped, i)
+	}
+	return -1
+}
+
+// skipGroovyValue returns where the value starting at i ends, at the next separator outside brackets and strings.
+func skipGroovyValue(stripped string, i int) int {
+	depth := 0
+	var quote byte
+	for ; i < len(stripped); i++ {
+		c := stripped[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '(' || c == '{':
+			depth++
+		case c == ']' || c == ')' || c == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case c == ',' && depth == 0:
+			return i
+		}
+	}
+	return i
+}
+
+var identifierExtractor = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+
+// compileExtReferenceExtractor matches the ways Groovy reads an ext map entry,
+// e.g. deps.okhttp, rootProject.ext.deps.okhttp, deps.'okhttp-logging' or deps['okhttp-logging']
+func compileExtReferenceExtractor(reference string) *regexp.Regexp {
+	segments := strings.Split(reference, ".")
+	var pattern strings.Builder
+	pattern.WriteString(`(^|[^\w.])` + extQualifierPattern + regexp.QuoteMeta(segments[0]))
+	for _, segment := range segments[1:] {
+		quoted := regexp.QuoteMeta(segment)
+		alternatives := []string{`\.'` + quoted + `'`, `\."` + quoted + `"`, `\[\s*['"]` + quoted + `['"]\s*]`}
+		if identifierExtractor.MatchString(segment) {
+			alternatives = append(alternatives, `\.`+quoted+`\b`)
+		}
+		pattern.WriteString(`(?:` + strings.Join(alternatives, "|") + `)`)
+	}
+	return regexp.MustCompile(pattern.String())
+}
+
+// retireExtMaps removes the ext maps whose entries all moved to the catalog once no script refers to them,
+// and deletes the applied scripts left empty along with the lines applying them.
+func (o DependencyObjects) retireExtMaps(root string, buildFilePaths []string, changes *ChangeSet) error {
+	type extMap struct {
+		path string
+		name string
+	}
+	removable := make(map[extMap]bool)
+	for _, file := range o.Files {
+		if file.Dialect != Groovy {
+			continue
+		}
+		for _, name := range file.Objects {
+			migrated := !slices.ContainsFunc(file.Members, func(member ObjectMember) bool {
+				return member.Kind == "" && strings.HasPrefix(member.Reference, name+".")
+			})
+			removable[extMap{file.Path, name}] = migrated
+		}
+	}
+	if len(removable) == 0 {
+		return nil
+	}
+
+	// the scripts as they would be without the removable maps
+	remainders := func() (map[string]string, error) {
+		remainders := make(map[string]string)
+		for _, path := range buildFilePaths {
+			content, err := changes.read(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if dialectOf(path) == Groovy {
+				current := parseExtMaps(path, content)
+				for i := len(current.Objects) - 1; i >= 0; i-- {
+					if removable[extMap{path, current.Objects[i]}] {
+						declaration := current.declarations[i]
+						content = content[:declaration[0]] + content[declaration[1]:]
+					}
+				}
+			}
+			remainders[path] = stripComments(content)
+		}
+		return remainders, nil
+	}
+	for changed := true; changed; {
+		changed = false
+		texts, err := remainders()
+		if err != nil {
+			return err
+		}
+		for _, candidate := range slices.SortedFunc(maps.Keys(removable), func(a, b extMap) int {
+			return strings.Compare(a.path+":"+a.name, b.path+":"+b.name)
+		}) {
+			if !removable[candidate] {
+				continue
+			}
+			extractor := regexp.MustCompile(`(^|[^\w.])` + extQualifierPattern + regexp.QuoteMeta(candidate.name) + `\b`)
+			for _, path := range buildFilePaths {
+				if extractor.MatchString(texts[path]) {
+					fmt.Printf("NOTICE: %s in %s is still referenced, keeping it.%s", candidate.name, candidate.path, LineBreak)
+					removable[candidate] = false
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	for _, file := range o.Files {
+		if file.Dialect != Groovy {
+			continue
+		}
+		content, err := changes.read(file.Path)
+		if err != nil {
+			return err
+		}
+		current := parseExtMaps(file.Path, content)
+		for i := len(current.Objects) - 1; i >= 0; i-- {
+			if !removable[extMap{file.Path, current.Objects[i]}] {
+				continue
+			}
+			declaration := current.declarations[i]
+			content = content[:declaration[0]] + content[declaration[1]:]
+			fmt.Printf("Removed: %s from %s%s", current.Objects[i], file.Path, LineBreak)
+		}
+		content = emptyExtBlockExtractor.ReplaceAllString(content, "")
+		if strings.TrimSpace(stripComments(content)) != "" || isModuleScript(file.Path) {
+			changes.write(file.Path, content)
+			continue
+		}
+		changes.remove(file.Path)
+		fmt.Printf("Removed: %s%s", file.Path, LineBreak)
+		if err := removeApplyFrom(root, buildFilePaths, file.Path, changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isModuleScript tells whether the script at path is the build or settings script of a build or module,
+// which is kept even when it is left empty.
+func isModuleScript(path string) bool {
+	name := strings.TrimSuffix(filepath.Base(path), ".kts")
+	return name == "build.gradle" || name == "settings.gradle" || isPrecompiledScriptPlugin(path)
+}
+
+var applyFromLineExtractor = regexp.MustCompile(`(?m)^[ \t]*` + applyFromExtractor.String() + `[ \t]*\)?[ \t]*\)?[ \t]*;?[ \t]*(?:\r?\n|$)`)
+
+// removeApplyFrom deletes the lines applying script from the given build files.
+func removeApplyFrom(root string, buildFilePaths []string, script string, changes *ChangeSet) error {
+	for _, path := range buildFilePaths {
+		content, err := changes.read(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		updated := applyFromLineExtractor.ReplaceAllStringFunc(content, func(line string) string {
+			applied, ok := resolveAppliedScript(root, path, applyFromLineExtractor.FindStringSubmatch(line)[1])
+			if ok && applied == script {
+				return ""
+			}
+			return line
+		})
+		if updated != content {
+			changes.write(path, updated)
+			fmt.Printf("Updated: %s%s", path, LineBreak)
+		}
+	}
+	return nil
+}
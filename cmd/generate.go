@@ -4,15 +4,19 @@ import (
 	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 )
 
 var generateCommand = &cobra.Command{
 	Use:   "generate [PATH]",
 	Short: "Generate libs.versions.toml",
 	Long: `
-Collects library versions in multiple build.gradle(.kts) and generates libs.versions.toml in PATH/gradle.
+Collects library versions in multiple build.gradle(.kts) and generates libs.versions.toml in PATH/gradle,
+or the file given by --catalog-path.
 If no PATH is provided, the current working directory is used.
 Some manual intervention may be required.
 
@@ -35,6 +39,13 @@ Caution:
 		if err != nil {
 			return fmt.Errorf("error option: %w", err)
 		}
+		fromMaven, err := cmd.Flags().GetBool("from-maven")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		if fromMaven {
+			return generateFromMaven(cmd, gradleProjectRootPath, useAutoLatest)
+		}
 
 		gradleDirPath := filepath.Join(gradleProjectRootPath, "gradle")
 		if _, err := os.Stat(gradleDirPath); os.IsNotExist(err) {
@@ -46,7 +57,55 @@ Caution:
 			return fmt.Errorf("error option: %w", err)
 		}
 
-		foundFiles, err := findBuildGradle(gradleProjectRootPath, int(maxDepth), 0)
+		filter := PathFilter{}
+		filter.Include, err = cmd.Flags().GetStringSlice("include")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		filter.Exclude, err = cmd.Flags().GetStringSlice("exclude")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+
+		allowDirty, err := cmd.Flags().GetBool("allow-dirty")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		shouldCommit, err := cmd.Flags().GetBool("commit")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		workTree, err := findGitWorkTree(gradleProjectRootPath)
+		if err != nil {
+			return fmt.Errorf("failed to detect a git work tree: %w", err)
+		}
+		workTree, err = inspectableWorkTree(workTree, allowDirty)
+		if err != nil {
+			return err
+		}
+		scope := Scope{}
+		scope.Modules, err = cmd.Flags().GetStringSlice("module")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		since, err := cmd.Flags().GetString("since")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		if since != "" {
+			if workTree == nil {
+				return errors.New("error option: --since requires a git work tree and the git command")
+			}
+			scope.Changed, err = workTree.changedFiles(since)
+			if err != nil {
+				return fmt.Errorf("failed to list files changed since %s: %w", since, err)
+			}
+		}
+		if shouldCommit && workTree == nil {
+			return errors.New("error option: --commit requires a git work tree and the git command")
+		}
+
+		foundFiles, err := discoverBuildFiles(gradleProjectRootPath, int(maxDepth), filter, workTree)
 		if err != nil {
 			return fmt.Errorf("error during listing up build.gradle files: %w", err)
 		}
@@ -55,57 +114,313 @@ Caution:
 			fmt.Printf("found build file: %s%s", file, LineBreak)
 		}
 
-		outputPath := filepath.Join(gradleProjectRootPath, "gradle", "libs.versions.toml")
+		scopedFiles, err := scope.filter(gradleProjectRootPath, foundFiles)
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		if !scope.isEmpty() {
+			for _, file := range scopedFiles {
+				fmt.Printf("in scope: %s%s", file, LineBreak)
+			}
+		}
+
+		layout, err := readCatalogLayout(gradleProjectRootPath)
+		if err != nil {
+			return fmt.Errorf("failed to read the settings file: %w", err)
+		}
+		catalogName, outputPath, err := catalogFlags(cmd, layout)
+		if err != nil {
+			return err
+		}
+		routeRules, err := cmd.Flags().GetStringSlice("route")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		router, err := parseRouter(routeRules, catalogName)
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		precompiledStyleValue, err := cmd.Flags().GetString("precompiled-style")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		precompiledStyle, err := parsePrecompiledStyle(precompiledStyleValue)
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
 		prevCatalog, err := ReadCatalog(outputPath)
 		if err != nil {
-			return fmt.Errorf("failed to read the existing libs.versions.toml: %w", err)
+			return fmt.Errorf("failed to read the existing %s: %w", outputPath, err)
+		}
+		// libraries already in another catalog of the build stay there
+		existingCatalogs := layout.existing()
+		for _, name := range slices.Sorted(maps.Keys(existingCatalogs)) {
+			if name == catalogName || existingCatalogs[name] == outputPath {
+				continue
+			}
+			other, err := ReadCatalog(existingCatalogs[name])
+			if err != nil {
+				return fmt.Errorf("failed to read the existing %s: %w", existingCatalogs[name], err)
+			}
+			router.adopt(*prevCatalog, name, *other)
+		}
+
+		propertyFileNames, err := cmd.Flags().GetStringSlice("properties-file")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		propertyFiles := make([]string, len(propertyFileNames))
+		for i, name := range propertyFileNames {
+			propertyFiles[i] = filepath.Join(gradleProjectRootPath, name)
+		}
+		properties := newPropertyFiles(gradleProjectRootPath, propertyFiles)
+		conflictStrategy, err := cmd.Flags().GetString("conflict")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		options := ExtractOptions{CatalogName: catalogName}
+		options.ConflictStrategy, err = parseConflictStrategy(conflictStrategy)
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		mergePolicy, err := cmd.Flags().GetString("merge")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		options.MergePolicy, err = parseMergePolicy(mergePolicy)
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
 		}
 
-		variableDefFiles := []string{filepath.Join(gradleProjectRootPath, "gradle.properties")}
-		catalog, err := extractVersionCatalog(*prevCatalog, foundFiles, variableDefFiles)
+		objects, err := findDependencyObjects(gradleProjectRootPath, foundFiles)
+		if err != nil {
+			return fmt.Errorf("failed to read the dependency objects of buildSrc: %w", err)
+		}
+		report := &Report{}
+		objects.addTo(*prevCatalog, catalogMerger{policy: options.MergePolicy, report: report})
+		extraction, err := extractVersionCatalog(*prevCatalog, foundFiles, properties, objects, options, report)
 		if err != nil {
-			return fmt.Errorf("failed to extract libs.versions.toml: %w", err)
+			return fmt.Errorf("failed to extract %s: %w", outputPath, err)
 		}
+		catalog := extraction.Catalog
 
 		if useAutoLatest {
 			searchLatestVersions(catalog)
 		}
 
-		embedResult, err := embedReferenceToLibs(foundFiles)
+		catalogs := map[string]VersionCatalog{catalogName: catalog}
+		catalogPaths := map[string]string{catalogName: outputPath}
+		if !router.isEmpty() {
+			catalogs, extraction.Aliases.routes = splitCatalog(catalog, router, func(alias string) []string {
+				coordinate, _ := libraryCoordinate(catalog.Libraries[alias])
+				return extraction.Configurations[coordinate]
+			})
+			for name := range catalogs {
+				if name != catalogName {
+					catalogPaths[name] = layout.path(name)
+				}
+			}
+		}
+
+		changes := newChangeSet()
+		// declarations first, so that the references to dependency objects they interpolate go away with them
+		rewrittenFiles, err := embedReferenceToLibs(scopedFiles, extraction.Aliases, precompiledStyle, changes)
+		if err != nil {
+			return fmt.Errorf("failed to rewrite build files: %w", err)
+		}
+		objectReferrers, err := objects.rewriteReferences(scopedFiles, extraction.Aliases, precompiledStyle, changes)
 		if err != nil {
 			return fmt.Errorf("failed to rewrite build files: %w", err)
 		}
+		for _, path := range objectReferrers {
+			if !slices.Contains(rewrittenFiles, path) {
+				rewrittenFiles = append(rewrittenFiles, path)
+			}
+		}
 
-		if embedResult.UpdatedBuildSrc {
-			var settingsGradlePath string
-			if embedResult.WrittenInKotlin {
-				settingsGradlePath = "settings.gradle.kts"
-			} else {
-				settingsGradlePath = "settings.gradle"
+		readers, err := findPropertyReaders(gradleProjectRootPath)
+		if err != nil {
+			return fmt.Errorf("failed to list the files reading properties: %w", err)
+		}
+		err = cleanUpVersionVariables(foundFiles, readers, scopeVariables(extraction.Variables, foundFiles, scopedFiles), extraction.Aliases, precompiledStyle, changes)
+		if err != nil {
+			return fmt.Errorf("failed to clean up version variables: %w", err)
+		}
+		err = objects.retire(gradleProjectRootPath, foundFiles, extraction.Aliases, changes)
+		if err != nil {
+			return fmt.Errorf("failed to retire the dependency objects and ext maps: %w", err)
+		}
+
+		if precompiledStyle == PrecompiledLibrariesFor {
+			err = wireLibrariesFor(gradleProjectRootPath, rewrittenFiles, extraction.Aliases.catalogNames(), changes)
+			if err != nil {
+				return fmt.Errorf("failed to add the generated accessors to included builds: %w", err)
 			}
+		}
+
+		includedBuilds, err := buildsToWire(gradleProjectRootPath, rewrittenFiles)
+		if err != nil {
+			return fmt.Errorf("failed to read the settings of included builds: %w", err)
+		}
+		err = registerCatalogs(layout, catalogPaths, includedBuilds, changes)
+		if err != nil {
+			return err
+		}
+
+		err = writeCatalogs(catalogs, catalogPaths, outputPath, changes)
+		if err != nil {
+			return err
+		}
 
-			fullPath := filepath.Join(gradleProjectRootPath, "buildSrc", settingsGradlePath)
-			err := writeBuildSrcSettings(fullPath)
+		if workTree != nil && !allowDirty {
+			err = workTree.ensureClean(changes.paths())
 			if err != nil {
-				return fmt.Errorf("failed to write %s: %w", fullPath, err)
+				return err
 			}
-			fmt.Printf("Updated: %s%s", fullPath, LineBreak)
-			fmt.Printf("         ^ This file is used to resolve Version Catalog (libs.versions.toml) in buildSrc.%s", LineBreak)
 		}
 
-		err = WriteCatalog(outputPath, catalog)
+		err = changes.apply()
 		if err != nil {
-			return fmt.Errorf("failed to write libs.versions.toml: %w", err)
+			return fmt.Errorf("failed to write files: %w", err)
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(catalogPaths)) {
+			fmt.Printf("Generated: %s%s", catalogPaths[name], LineBreak)
 		}
+		report.Print()
 
-		fmt.Printf("Generated: %s%s", outputPath, LineBreak)
+		if shouldCommit {
+			err = workTree.commit(changes.paths(), migrationCommitMessage(gradleProjectRootPath, catalog, changes.paths()))
+			if err != nil {
+				return fmt.Errorf("failed to commit the migration: %w", err)
+			}
+			fmt.Printf("Committed: %d files%s", len(changes.paths()), LineBreak)
+		}
 
 		return err
 	},
 }
 
+// generateFromMaven generates the catalog of a Maven build being migrated to Gradle from its pom.xml files.
+// No build file is rewritten, since there is none yet.
+func generateFromMaven(cmd *cobra.Command, root string, useAutoLatest bool) error {
+	allowDirty, err := cmd.Flags().GetBool("allow-dirty")
+	if err != nil {
+		return fmt.Errorf("error option: %w", err)
+	}
+	conflictStrategy, err := cmd.Flags().GetString("conflict")
+	if err != nil {
+		return fmt.Errorf("error option: %w", err)
+	}
+	mergePolicy, err := cmd.Flags().GetString("merge")
+	if err != nil {
+		return fmt.Errorf("error option: %w", err)
+	}
+
+	layout, err := readCatalogLayout(root)
+	if err != nil {
+		return fmt.Errorf("failed to read the settings file: %w", err)
+	}
+	catalogName, outputPath, err := catalogFlags(cmd, layout)
+	if err != nil {
+		return err
+	}
+	options := ExtractOptions{CatalogName: catalogName}
+	options.ConflictStrategy, err = parseConflictStrategy(conflictStrategy)
+	if err != nil {
+		return fmt.Errorf("error option: %w", err)
+	}
+	options.MergePolicy, err = parseMergePolicy(mergePolicy)
+	if err != nil {
+		return fmt.Errorf("error option: %w", err)
+	}
+
+	reactor, err := readMavenReactor(root)
+	if err != nil {
+		return fmt.Errorf("failed to read the Maven build: %w", err)
+	}
+	for _, project := range reactor {
+		fmt.Printf("found build file: %s%s", project.path, LineBreak)
+	}
+	prevCatalog, err := ReadCatalog(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the existing %s: %w", outputPath, err)
+	}
+	report := &Report{}
+	catalog, err := extractMavenCatalog(*prevCatalog, reactor, root, options, report)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", outputPath, err)
+	}
+	if useAutoLatest {
+		searchLatestVersions(catalog)
+	}
+
+	changes := newChangeSet()
+	catalogPaths := map[string]string{catalogName: outputPath}
+	err = registerCatalogs(layout, catalogPaths, nil, changes)
+	if err != nil {
+		return err
+	}
+	err = writeCatalogs(map[string]VersionCatalog{catalogName: catalog}, catalogPaths, outputPath, changes)
+	if err != nil {
+		return err
+	}
+
+	workTree, err := findGitWorkTree(root)
+	if err != nil {
+		return fmt.Errorf("failed to detect a git work tree: %w", err)
+	}
+	workTree, err = inspectableWorkTree(workTree, allowDirty)
+	if err != nil {
+		return err
+	}
+	if workTree != nil && !allowDirty {
+		err = workTree.ensureClean(changes.paths())
+		if err != nil {
+			return err
+		}
+	}
+	err = changes.apply()
+	if err != nil {
+		return fmt.Errorf("failed to write files: %w", err)
+	}
+	fmt.Printf("Generated: %s%s", outputPath, LineBreak)
+	report.Print()
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(generateCommand)
 	generateCommand.Flags().Bool("auto-latest", true, "auto select latest version if none is specified")
-	generateCommand.Flags().Int8("max-depth", 3, "Maximum depth of searching gradle files. Project root is 0. Defaults to 3.")
+	generateCommand.Flags().Bool("from-maven", false, "Generate the catalog from the pom.xml files of a Maven build instead of Gradle build files")
+	generateCommand.Flags().Int8("max-depth", 3, "Maximum depth of searching gradle files when settings.gradle(.kts) includes no modules. Project root is 0. Defaults to 3.")
+	generateCommand.Flags().StringSlice("include", nil, "Glob patterns of build files to process, relative to PATH (e.g. app/**)")
+	generateCommand.Flags().StringSlice("exclude", nil, "Glob patterns of build files to skip, relative to PATH (e.g. samples/**)")
+	generateCommand.Flags().String("catalog-name", defaultCatalogName, "Name of the catalog, which build files access it by (e.g. deps). Defaults to the one configured in settings.gradle(.kts)")
+	generateCommand.Flags().String("catalog-path", "", "Path of the catalog file relative to PATH. Defaults to the file declared in settings.gradle(.kts), or gradle/<catalog-name>.versions.toml")
+	generateCommand.Flags().StringSlice("route", nil, "Rules sending entries to other catalogs, the first match wins: config:<glob>=<catalog>, group:<glob>=<catalog> or kind:library|plugin=<catalog>")
+	generateCommand.Flags().String("precompiled-style", string(PrecompiledCatalogAPI), "How precompiled script plugins in buildSrc and included builds reach the catalog: catalog-api (versionCatalogs.named(\"libs\").findLibrary(...)) or libraries-for (the<LibrariesForLibs>() in Kotlin scripts)")
+	generateCommand.Flags().StringSlice("properties-file", nil, "Extra property files defining version variables, relative to PATH (e.g. versions.properties). They are consulted after the gradle.properties of each module and its parent directories")
+	generateCommand.Flags().String("conflict", string(ConflictHighest), "How to resolve a library declared with different versions: highest, lowest, fail or keep-both")
+	generateCommand.Flags().StringSlice("module", nil, "Gradle project paths of the modules to rewrite, wildcards allowed (e.g. :app,:core:*). The catalog is still generated from all modules")
+	generateCommand.Flags().String("since", "", "Rewrite only build files changed since the given git ref. The catalog is still generated from all build files")
+	generateCommand.Flags().Bool("allow-dirty", false, "Rewrite files even if they have uncommitted changes in git")
+	generateCommand.Flags().Bool("commit", false, "Commit the rewritten files to git with a generated summary message")
+	generateCommand.Flags().String("merge", string(MergePreferScanned), "How to merge the existing catalog with scanned values: prefer-scanned, keep-existing or fail-on-difference")
+}
+
+// migrationCommitMessage summarizes a migration for --commit.
+func migrationCommitMessage(root string, catalog VersionCatalog, paths []string) string {
+	var builder strings.Builder
+	builder.WriteString("Migrate dependencies to the Gradle version catalog\n\n")
+	builder.WriteString(fmt.Sprintf("Cataloged %d versions, %d libraries and %d plugins.\n\n",
+		len(catalog.Versions), len(catalog.Libraries), len(catalog.Plugins)))
+	builder.WriteString("Updated files:\n")
+	for _, path := range paths {
+		if relative, err := filepath.Rel(root, path); err == nil {
+			path = filepath.ToSlash(relative)
+		}
+		builder.WriteString(fmt.Sprintf("- %s\n", path))
+	}
+	return builder.String()
 }
@@ -3,8 +3,10 @@ package cmd
 import (
 	"github.com/stretchr/testify/assert"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -88,12 +90,12 @@ software-amazon-awssdk-s3 = { group = "software.amazon.awssdk", name = "s3", ver
 
 	f2, _ := os.ReadFile(filepath.Join(tempdir, "build.gradle"))
 	compareIgnoreLineBreaks(t, `
-		implementation(libs.foo.sub.no.version)
-		classpath(libs.software.amazon.awssdk.s3)
+		implementation libs.foo.sub.no.version
+		classpath libs.software.amazon.awssdk.s3
 		api(libs.foo.foo)
 		testImplementation(libs.org.apache.flink.flink.runtime212)
 		implementation(variantOf(libs.org.openjfx.javafx.base) { classifier("win") })
-   		annotationProcessor(libs.org.jboss.logging.jboss.logging)
+   		annotationProcessor libs.org.jboss.logging.jboss.logging
 `, string(f2))
 
 }
@@ -115,15 +117,18 @@ func TestVariableSupport(t *testing.T) {
 
 	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
 	compareIgnoreLineBreaks(t, `[versions]
-barVersion = "FIXME"
 fooVersion = "1.0"
 
 [libraries]
-bar-bar = { group = "bar", name = "bar", version.ref = "barVersion" }
 foo-foo = { group = "foo", name = "foo", version.ref = "fooVersion" }
-foo-foo-ext = { group = "foo", name = "foo-ext", version.ref = "fooVersion" }
 
 `, string(f))
+
+	// a local variable of the root script is not visible to foo, and barVersion is defined nowhere
+	f, _ = os.ReadFile(filepath.Join(tempdir, "foo/build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		testImplementation("foo:foo-ext:${fooVersion}")
+	`, string(f))
 }
 
 func TestVariableInGradleProperties(t *testing.T) {
@@ -146,12 +151,10 @@ func TestVariableInGradleProperties(t *testing.T) {
 
 	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
 	compareIgnoreLineBreaks(t, `[versions]
-barVersion = "FIXME"
 buzVersion = "1.0"
 fooVersion = "2.0-SNAPSHOT"
 
 [libraries]
-bar-bar = { group = "bar", name = "bar", version.ref = "barVersion" }
 foo-foo = { group = "foo", name = "foo", version.ref = "fooVersion" }
 foo-foo-ext = { group = "foo", name = "foo-ext", version.ref = "buzVersion" }
 
@@ -282,23 +285,19 @@ func TestMapNotation(t *testing.T) {
 	assert.NoError(t, generateCommand.Execute())
 
 	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
-	compareIgnoreLineBreaks(t, `[versions]
-dVersion = "FIXME"
-
-[libraries]
+	compareIgnoreLineBreaks(t, `[libraries]
 b-b-bbb = { group = "b.b", name = "bbb", version = "2.0.0" }
-c-c-ccc = { group = "c.c", name = "ccc", version.ref = "dVersion" }
-d-d-ddd = { group = "d.d", name = "ddd", version.ref = "dVersion" }
 e-e-eee = { group = "e.e", name = "eee", version = "FIXME" }
 `, string(f))
 
+	// dVersion is defined nowhere
 	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle"))
 	compareIgnoreLineBreaks(t, `
 		runtimeOnly(libs.b.b.bbb) {
 			transitive = true
 		}
-		implementation(libs.c.c.ccc)
-		implementation(libs.d.d.ddd) {
+		implementation(group = "c.c", name = "ccc", version = dVersion)
+		implementation(group = "d.d", name = "ddd", version = "$dVersion") {
 			isTransitive = true
 		}
 		implementation(libs.e.e.eee)
@@ -316,20 +315,86 @@ func TestTolerateDotInVariable(t *testing.T) {
 	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
 	assert.NoError(t, generateCommand.Execute())
 
+	// versions is defined nowhere
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, ``, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle"))
+	compareIgnoreLineBreaks(t, `
+        api group: 'foo', name: 'bar', version:"${versions.foo}"
+        api 'foo:bar-buz:${versions.foo}'
+	`, string(f))
+}
+
+func TestReportUnresolvedVariables(t *testing.T) {
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "build.gradle", `
+        implementation(group = "c.c", name = "ccc", version = dVersion)
+        api 'foo:bar-buz:${versions.foo}'
+        implementation "foo:foo:1.0"
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	stdout, err := CaptureStdout(t, generateCommand.Execute)
+	assert.NoError(t, err)
+
+	buildFile := filepath.Join(tempdir, "build.gradle")
+	assert.Contains(t, stdout, `  Unresolved version variables:
+    `+buildFile+`:2: $dVersion
+    `+buildFile+`:3: $versions_foo`)
+}
+
+func TestResolvePropertyReadsInVersions(t *testing.T) {
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `include(":sub")`)
+	writeFile(t, tempdir, "gradle.properties", "okioVersion=3.9.0\n")
+	writeFile(t, tempdir, "build.gradle.kts", `dependencies {
+    implementation("g:a:${property("okioVersion")}")
+    implementation("g:b:${project.findProperty("okioVersion")}")
+    implementation("g:c:${rootProject.extra["okioVersion"]}")
+    implementation(group = "g", name = "d", version = "${property("okioVersion")}")
+    implementation("g:e:${property("missing")}")
+}
+`)
+	writeFile(t, tempdir, "sub/build.gradle", `dependencies {
+    implementation "g:f:${project.'okioVersion'}"
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	stdout, err := CaptureStdout(t, generateCommand.Execute)
+	assert.NoError(t, err)
+
 	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
 	compareIgnoreLineBreaks(t, `[versions]
-versions_foo = "FIXME"
+okioVersion = "3.9.0"
 
 [libraries]
-foo-bar = { group = "foo", name = "bar", version.ref = "versions_foo" }
-foo-bar-buz = { group = "foo", name = "bar-buz", version.ref = "versions_foo" }
+g-a = { group = "g", name = "a", version.ref = "okioVersion" }
+g-b = { group = "g", name = "b", version.ref = "okioVersion" }
+g-c = { group = "g", name = "c", version.ref = "okioVersion" }
+g-d = { group = "g", name = "d", version.ref = "okioVersion" }
+g-f = { group = "g", name = "f", version.ref = "okioVersion" }
 `, string(f))
 
-	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle"))
-	compareIgnoreLineBreaks(t, `
-        api(libs.foo.bar)
-        api(libs.foo.bar.buz)
-	`, string(f))
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle.kts"))
+	assert.Equal(t, `dependencies {
+    implementation(libs.g.a)
+    implementation(libs.g.b)
+    implementation(libs.g.c)
+    implementation(libs.g.d)
+    implementation("g:e:${property("missing")}")
+}
+`, string(f))
+	f, _ = os.ReadFile(filepath.Join(tempdir, "sub", "build.gradle"))
+	assert.Equal(t, `dependencies {
+    implementation libs.g.f
+}
+`, string(f))
+
+	assert.Contains(t, stdout, filepath.Join(tempdir, "build.gradle.kts")+":6: $project_missing")
 }
 
 func TestResolutionStrategyForce(t *testing.T) {
@@ -353,9 +418,9 @@ org-jetbrains-kotlin-kotlin-stdlib = { group = "org.jetbrains.kotlin", name = "k
 
 	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle"))
 	compareIgnoreLineBreaks(t, `
-        resolutionStrategy.force(libs.net.bytebuddy.byte.buddy)
+        resolutionStrategy.force libs.net.bytebuddy.byte.buddy
         resolutionStrategy {
-            force(libs.org.jetbrains.kotlin.kotlin.stdlib)
+            force libs.org.jetbrains.kotlin.kotlin.stdlib
         }
 	`, string(f))
 }
@@ -387,8 +452,8 @@ foo-bar = { group = "foo", name = "bar", version = "1.0" }
 	f, _ = os.ReadFile(filepath.Join(tempdir, "buildSrc/settings.gradle"))
 	compareIgnoreLineBreaks(t, `dependencyResolutionManagement {
             versionCatalogs {
-                create("libs") {
-                    from(files("../gradle/libs.versions.toml"))
+                libs {
+                    from(files('../gradle/libs.versions.toml'))
                 }
             }
         }
@@ -402,8 +467,8 @@ foo-bar = { group = "foo", name = "bar", version = "1.0" }
 	f, _ = os.ReadFile(filepath.Join(tempdir, "buildSrc/settings.gradle"))
 	compareIgnoreLineBreaks(t, `dependencyResolutionManagement {
             versionCatalogs {
-                create("libs") {
-                    from(files("../gradle/libs.versions.toml"))
+                libs {
+                    from(files('../gradle/libs.versions.toml'))
                 }
             }
         }
@@ -513,3 +578,2595 @@ foo-bar = { group = "foo", name = "bar", version = "1.0" }
         }
 	`, string(f))
 }
+
+func TestDialectPerBuildFile(t *testing.T) {
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "build.gradle", `
+		plugins {
+			id 'foo.groovy' version '1.0'
+		}
+		implementation 'net.sf.json-lib:json-lib:2.3:jdk15'
+		implementation("org.openjfx:javafx-base:11.0.2:win")
+	`)
+	writeFile(t, tempdir, "kotlin/build.gradle.kts", `
+		plugins {
+			id("foo.kotlin") version "2.0"
+		}
+		implementation("net.sf.json-lib:json-lib:2.3:jdk15")
+	`)
+	writeFile(t, tempdir, "buildSrc/build.gradle.kts", `
+		implementation("foo:bar:1.0")
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		plugins {
+			alias(libs.plugins.foo.groovy)
+		}
+		implementation variantOf(libs.net.sf.json.lib.json.lib) { classifier('jdk15') }
+		implementation(variantOf(libs.org.openjfx.javafx.base) { classifier("win") })
+	`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "kotlin/build.gradle.kts"))
+	compareIgnoreLineBreaks(t, `
+		plugins {
+			alias(libs.plugins.foo.kotlin)
+		}
+		implementation(variantOf(libs.net.sf.json.lib.json.lib) { classifier("jdk15") })
+	`, string(f))
+
+	_, err := os.Stat(filepath.Join(tempdir, "buildSrc/settings.gradle"))
+	assert.True(t, os.IsNotExist(err), "Groovy settings should not be generated for Kotlin buildSrc")
+	f, _ = os.ReadFile(filepath.Join(tempdir, "buildSrc/settings.gradle.kts"))
+	compareIgnoreLineBreaks(t, `dependencyResolutionManagement {
+            versionCatalogs {
+                create("libs") {
+                    from(files("../gradle/libs.versions.toml"))
+                }
+            }
+        }
+	`, string(f))
+}
+
+func TestRemoveDeadVersionVariables(t *testing.T) {
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "build.gradle.kts", `
+		val awsSdkVersion = "2.3.4"
+		val jacocoVersion = "0.8.11"
+		implementation("software.amazon.awssdk:sts:$awsSdkVersion")
+		implementation("org.jacoco:org.jacoco.agent:$jacocoVersion")
+		jacoco {
+			toolVersion = jacocoVersion
+		}
+	`)
+	writeFile(t, tempdir, "foo/build.gradle", `
+		ext.guavaVersion = '33.0.0-jre'
+		implementation "com.google.guava:guava:$guavaVersion"
+		implementation "foo:foo:$fooVersion"
+		implementation "bar:bar:$barVersion"
+		version = "$barVersion"
+	`)
+	writeFile(t, tempdir, "gradle.properties", `org.gradle.jvmargs=-Xmx2g
+fooVersion=1.0
+barVersion=2.0
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+awsSdkVersion = "2.3.4"
+barVersion = "2.0"
+fooVersion = "1.0"
+guavaVersion = "33.0.0-jre"
+jacocoVersion = "0.8.11"
+
+[libraries]
+bar-bar = { group = "bar", name = "bar", version.ref = "barVersion" }
+com-google-guava-guava = { group = "com.google.guava", name = "guava", version.ref = "guavaVersion" }
+foo-foo = { group = "foo", name = "foo", version.ref = "fooVersion" }
+org-jacoco-org-jacoco-agent = { group = "org.jacoco", name = "org.jacoco.agent", version.ref = "jacocoVersion" }
+software-amazon-awssdk-sts = { group = "software.amazon.awssdk", name = "sts", version.ref = "awsSdkVersion" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle.kts"))
+	compareIgnoreLineBreaks(t, `
+		val jacocoVersion = libs.versions.jacocoVersion.get()
+		implementation(libs.software.amazon.awssdk.sts)
+		implementation(libs.org.jacoco.org.jacoco.agent)
+		jacoco {
+			toolVersion = jacocoVersion
+		}
+	`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "foo/build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		implementation libs.com.google.guava.guava
+		implementation libs.foo.foo
+		implementation libs.bar.bar
+		version = "$barVersion"
+	`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "gradle.properties"))
+	assert.Equal(t, `org.gradle.jvmargs=-Xmx2g
+barVersion=2.0
+`, string(f))
+}
+
+func TestKeepPropertiesReadOutsideBuildScripts(t *testing.T) {
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `val kotlinVersion: String by settings
+pluginManagement {
+    plugins {
+        kotlin("jvm") version kotlinVersion
+    }
+}
+`)
+	writeFile(t, tempdir, "buildSrc/src/main/kotlin/Conventions.kt", `fun okioVersion(project: Project) = project.property("okioVersion")
+`)
+	writeFile(t, tempdir, "build.gradle.kts", `
+		implementation("org.jetbrains.kotlin:kotlin-stdlib:$kotlinVersion")
+		implementation("com.squareup.okio:okio:$okioVersion")
+		implementation("foo:foo:$fooVersion")
+	`)
+	writeFile(t, tempdir, "gradle.properties", `kotlinVersion=1.9.0
+okioVersion=3.9.0
+fooVersion=1.0
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	stdout, err := CaptureStdout(t, generateCommand.Execute)
+	assert.NoError(t, err)
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle.properties"))
+	assert.Equal(t, `kotlinVersion=1.9.0
+okioVersion=3.9.0
+`, string(f))
+	propertiesFile := filepath.Join(tempdir, "gradle.properties")
+	assert.Contains(t, stdout, "NOTICE: kotlinVersion in "+propertiesFile+" is still referenced, keeping it.")
+	assert.Contains(t, stdout, "NOTICE: okioVersion in "+propertiesFile+" is still referenced, keeping it.")
+}
+
+func TestRemoveVariablesInterpolatedByDeadTemplates(t *testing.T) {
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "build.gradle.kts", `
+		val major = "1"
+		val minor = "2"
+		val libVersion = "$major.$minor"
+		implementation("foo:lib:$libVersion")
+		version = "$major.0"
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	stdout, err := CaptureStdout(t, generateCommand.Execute)
+	assert.NoError(t, err)
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+libVersion = "1.2"
+
+[libraries]
+foo-lib = { group = "foo", name = "lib", version.ref = "libVersion" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle.kts"))
+	compareIgnoreLineBreaks(t, `
+		val major = "1"
+		implementation(libs.foo.lib)
+		version = "$major.0"
+	`, string(f))
+	buildFile := filepath.Join(tempdir, "build.gradle.kts")
+	assert.Contains(t, stdout, "Removed: libVersion from "+buildFile)
+	assert.Contains(t, stdout, "Removed: minor from "+buildFile)
+	assert.NotContains(t, stdout, "Removed: major")
+}
+
+func TestIgnoreMarkers(t *testing.T) {
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "build.gradle.kts", `
+		plugins {
+			id("foo.plugin") version "1.0" // gvc:ignore
+		}
+		implementation("foo:migrated:1.0")
+		implementation("foo:pinned:1.0") // gvc:ignore
+		// gvc:ignore-next-line
+		testImplementation("foo:next-line:1.0")
+		// gvc:off
+		implementation("foo:vendored:1.0-fork")
+		implementation(group = "foo", name = "vendored-map", version = "1.0")
+		// gvc:on
+		implementation("foo:after:1.0")
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	stdout, err := CaptureStdout(t, generateCommand.Execute)
+	assert.NoError(t, err)
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+foo-after = { group = "foo", name = "after", version = "1.0" }
+foo-migrated = { group = "foo", name = "migrated", version = "1.0" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle.kts"))
+	compareIgnoreLineBreaks(t, `
+		plugins {
+			id("foo.plugin") version "1.0" // gvc:ignore
+		}
+		implementation(libs.foo.migrated)
+		implementation("foo:pinned:1.0") // gvc:ignore
+		// gvc:ignore-next-line
+		testImplementation("foo:next-line:1.0")
+		// gvc:off
+		implementation("foo:vendored:1.0-fork")
+		implementation(group = "foo", name = "vendored-map", version = "1.0")
+		// gvc:on
+		implementation(libs.foo.after)
+	`, string(f))
+
+	buildFile := filepath.Join(tempdir, "build.gradle.kts")
+	assert.Contains(t, stdout, "Skipped by gvc markers:")
+	assert.Contains(t, stdout, buildFile+`:3: id("foo.plugin") version "1.0"`)
+	assert.Contains(t, stdout, buildFile+`:6: implementation("foo:pinned:1.0")`)
+	assert.Contains(t, stdout, buildFile+`:8: testImplementation("foo:next-line:1.0")`)
+	assert.Contains(t, stdout, buildFile+`:10: implementation("foo:vendored:1.0-fork")`)
+	assert.Contains(t, stdout, buildFile+`:11: implementation(group = "foo", name = "vendored-map", version = "1.0")`)
+}
+
+func TestPluginAtLineStartAfterIgnoredBlock(t *testing.T) {
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "build.gradle.kts", `plugins {
+// gvc:off
+id("a.b") version "1.0"
+// gvc:on
+id("c.d") version "2.0"
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[plugins]
+c-d = { id = "c.d", version = "2.0" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle.kts"))
+	assert.Equal(t, `plugins {
+// gvc:off
+id("a.b") version "1.0"
+// gvc:on
+alias(libs.plugins.c.d)
+}
+`, string(f))
+}
+
+func writeConflictingModules(t *testing.T, tempdir string) {
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "a/build.gradle", `
+		implementation("com.google.guava:guava:31.1-jre")
+	`)
+	writeFile(t, tempdir, "b/build.gradle", `
+		implementation("com.google.guava:guava:33.0.0-jre")
+	`)
+	writeFile(t, tempdir, "c/build.gradle", `
+		implementation("com.google.guava:guava:$guavaVersion")
+	`)
+	writeFile(t, tempdir, "gradle.properties", `guavaVersion=31.1-jre`)
+}
+
+func TestConflictHighestByDefault(t *testing.T) {
+	tempdir := t.TempDir()
+	writeConflictingModules(t, tempdir)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	stdout, err := CaptureStdout(t, generateCommand.Execute)
+	assert.NoError(t, err)
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+com-google-guava-guava = { group = "com.google.guava", name = "guava", version = "33.0.0-jre" }
+`, string(f))
+
+	assert.Contains(t, stdout, "com.google.guava:guava (highest: 33.0.0-jre)")
+	assert.Contains(t, stdout, "31.1-jre in "+filepath.Join(tempdir, "a", "build.gradle"))
+	assert.Contains(t, stdout, "33.0.0-jre in "+filepath.Join(tempdir, "b", "build.gradle"))
+	assert.Contains(t, stdout, "31.1-jre in "+filepath.Join(tempdir, "c", "build.gradle"))
+}
+
+func TestConflictLowest(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeConflictingModules(t, tempdir)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--conflict=lowest"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+guavaVersion = "31.1-jre"
+
+[libraries]
+com-google-guava-guava = { group = "com.google.guava", name = "guava", version.ref = "guavaVersion" }
+`, string(f))
+}
+
+func TestConflictBetweenLiteralAndVariable(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "a/build.gradle.kts", `val guavaVersion = "31.0-jre"
+dependencies {
+    implementation("com.google.guava:guava:$guavaVersion")
+}
+`)
+	writeFile(t, tempdir, "b/build.gradle.kts", `val guavaPreview = "32.0-jre"
+dependencies {
+    implementation("com.google.guava:guava:$guavaPreview")
+}
+println(guavaPreview)
+`)
+	writeFile(t, tempdir, "c/build.gradle.kts", `dependencies {
+    implementation("com.google.guava:guava:33.0-jre")
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+com-google-guava-guava = { group = "com.google.guava", name = "guava", version = "33.0-jre" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "a", "build.gradle.kts"))
+	assert.Equal(t, `dependencies {
+    implementation(libs.com.google.guava.guava)
+}
+`, string(f))
+	f, _ = os.ReadFile(filepath.Join(tempdir, "b", "build.gradle.kts"))
+	assert.Equal(t, `val guavaPreview = "32.0-jre"
+dependencies {
+    implementation(libs.com.google.guava.guava)
+}
+println(guavaPreview)
+`, string(f))
+}
+
+func TestConflictFail(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeConflictingModules(t, tempdir)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--conflict=fail"}
+	err := generateCommand.Execute()
+	assert.ErrorContains(t, err, "com.google.guava:guava (versions: 33.0.0-jre, 31.1-jre)")
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "a", "build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		implementation("com.google.guava:guava:31.1-jre")
+	`, string(f))
+}
+
+func TestConflictKeepBoth(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeConflictingModules(t, tempdir)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--conflict=keep-both"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+guavaVersion = "31.1-jre"
+
+[libraries]
+com-google-guava-guava = { group = "com.google.guava", name = "guava", version = "33.0.0-jre" }
+com-google-guava-guava-v311-jre = { group = "com.google.guava", name = "guava", version.ref = "guavaVersion" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "a", "build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		implementation(libs.com.google.guava.guava.v311.jre)
+	`, string(f))
+	f, _ = os.ReadFile(filepath.Join(tempdir, "b", "build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		implementation(libs.com.google.guava.guava)
+	`, string(f))
+	f, _ = os.ReadFile(filepath.Join(tempdir, "c", "build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		implementation(libs.com.google.guava.guava.v311.jre)
+	`, string(f))
+}
+
+func TestConflictKeepBothInOneFile(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "build.gradle", `
+		implementation 'com.google.guava:guava:33.0.0-jre'
+		testImplementation 'com.google.guava:guava:32.0.0-jre'
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--conflict=keep-both"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+com-google-guava-guava = { group = "com.google.guava", name = "guava", version = "33.0.0-jre" }
+com-google-guava-guava-v3200-jre = { group = "com.google.guava", name = "guava", version = "32.0.0-jre" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		implementation libs.com.google.guava.guava
+		testImplementation libs.com.google.guava.guava.v3200.jre
+	`, string(f))
+}
+
+func TestConflictRejectUnknownStrategy(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeConflictingModules(t, tempdir)
+
+	os.Args = []string{"cli", "generate", tempdir, "--conflict=newest"}
+	assert.ErrorContains(t, generateCommand.Execute(), `unknown conflict strategy "newest"`)
+}
+
+func TestReuseExistingAliases(t *testing.T) {
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/libs.versions.toml", `[versions]
+guava = "31.0-jre"
+shadow = "8.0.0"
+
+[libraries]
+guava = { module = "com.google.guava:guava", version.ref = "guava" }
+commons = { group = "org.apache.commons", name = "commons-lang3", version = "3.0" }
+
+[plugins]
+shadow = { id = "com.gradleup.shadow", version.ref = "shadow" }
+`)
+	writeFile(t, tempdir, "build.gradle.kts", `
+		plugins {
+			id("com.gradleup.shadow") version "8.3.5"
+		}
+		implementation("com.google.guava:guava:33.0.0-jre")
+		implementation("org.apache.commons:commons-lang3:3.17.0")
+		implementation("foo:bar:1.0")
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+guava = "33.0.0-jre"
+shadow = "8.3.5"
+
+[libraries]
+commons = { group = "org.apache.commons", name = "commons-lang3", version = "3.17.0" }
+foo-bar = { group = "foo", name = "bar", version = "1.0" }
+guava = { module = "com.google.guava:guava", version.ref = "guava" }
+
+[plugins]
+shadow = { id = "com.gradleup.shadow", version.ref = "shadow" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle.kts"))
+	compareIgnoreLineBreaks(t, `
+		plugins {
+			alias(libs.plugins.shadow)
+		}
+		implementation(libs.guava)
+		implementation(libs.commons)
+		implementation(libs.foo.bar)
+	`, string(f))
+}
+
+func writeCuratedCatalog(t *testing.T, tempdir string) {
+	writeFile(t, tempdir, "gradle/libs.versions.toml", `[versions]
+guava = "31.0-jre"
+
+[libraries]
+guava = { module = "com.google.guava:guava", version.ref = "guava" }
+commons = { group = "org.apache.commons", name = "commons-lang3", version = "3.0" }
+alternate = { group = "com.mycompany", name = "alternate", version = { strictly = "[1.0, 2.0[", prefer = "1.5" } }
+no-version = { group = "foo", name = "no-version", version = "1.0" }
+`)
+	writeFile(t, tempdir, "build.gradle.kts", `
+		implementation("com.google.guava:guava:33.0.0-jre")
+		implementation("org.apache.commons:commons-lang3:3.17.0")
+		implementation("com.mycompany:alternate:1.9")
+		implementation("foo:no-version")
+	`)
+}
+
+func TestMergePreferScannedByDefault(t *testing.T) {
+	tempdir := t.TempDir()
+	writeCuratedCatalog(t, tempdir)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	stdout, err := CaptureStdout(t, generateCommand.Execute)
+	assert.NoError(t, err)
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+guava = "33.0.0-jre"
+
+[libraries]
+alternate = { group = "com.mycompany", name = "alternate", version = { strictly = "[1.0, 2.0[", prefer = "1.5" } }
+commons = { group = "org.apache.commons", name = "commons-lang3", version = "3.17.0" }
+guava = { module = "com.google.guava:guava", version.ref = "guava" }
+no-version = { group = "foo", name = "no-version", version = "1.0" }
+`, string(f))
+
+	assert.Contains(t, stdout, `versions.guava: "31.0-jre" (existing) vs "33.0.0-jre" (scanned), overridden`)
+	assert.Contains(t, stdout, `libraries.commons: "3.0" (existing) vs "3.17.0" (scanned), overridden`)
+	assert.Contains(t, stdout, `libraries.alternate: version = { strictly = "[1.0, 2.0[", prefer = "1.5" } (existing) vs "1.9" (scanned), kept the rich version`)
+	assert.NotContains(t, stdout, "libraries.no-version")
+}
+
+func TestMergeKeepExisting(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeCuratedCatalog(t, tempdir)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--merge=keep-existing"}
+	stdout, err := CaptureStdout(t, generateCommand.Execute)
+	assert.NoError(t, err)
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+guava = "31.0-jre"
+
+[libraries]
+alternate = { group = "com.mycompany", name = "alternate", version = { strictly = "[1.0, 2.0[", prefer = "1.5" } }
+commons = { group = "org.apache.commons", name = "commons-lang3", version = "3.0" }
+guava = { module = "com.google.guava:guava", version.ref = "guava" }
+no-version = { group = "foo", name = "no-version", version = "1.0" }
+`, string(f))
+
+	assert.Contains(t, stdout, `versions.guava: "31.0-jre" (existing) vs "33.0.0-jre" (scanned), kept existing`)
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle.kts"))
+	compareIgnoreLineBreaks(t, `
+		implementation(libs.guava)
+		implementation(libs.commons)
+		implementation(libs.alternate)
+		implementation(libs.no.version)
+	`, string(f))
+}
+
+func TestMergeFailOnDifference(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeCuratedCatalog(t, tempdir)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--merge=fail-on-difference"}
+	err := generateCommand.Execute()
+	assert.ErrorContains(t, err, "the existing catalog differs from build files")
+	assert.ErrorContains(t, err, `versions.guava: "31.0-jre" (existing) vs "33.0.0-jre" (scanned)`)
+	assert.NotContains(t, err.Error(), "libraries.alternate")
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "build.gradle.kts"))
+	assert.Contains(t, string(f), `implementation("com.google.guava:guava:33.0.0-jre")`)
+}
+
+func TestMergeRichVersionsEntry(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/libs.versions.toml", `[versions]
+guava = { strictly = "[31,33[", prefer = "32.0" }
+kotlin = "2.0.0"
+
+[libraries]
+guava = { module = "com.google.guava:guava", version.ref = "guava" }
+
+[plugins]
+kotlin-jvm = { id = "org.jetbrains.kotlin.jvm", version.ref = "kotlin" }
+`)
+	writeFile(t, tempdir, "build.gradle.kts", `
+		plugins {
+			id("org.jetbrains.kotlin.jvm") version "2.0.0"
+		}
+		implementation("com.google.guava:guava:33.0.0-jre")
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--merge=fail-on-difference"}
+	stdout, err := CaptureStdout(t, generateCommand.Execute)
+	assert.NoError(t, err)
+	assert.Contains(t, stdout, `versions.guava: version = { strictly = "[31,33[", prefer = "32.0" } (existing) vs "33.0.0-jre" (scanned), kept the rich version`)
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+guava = { strictly = "[31,33[", prefer = "32.0" }
+kotlin = "2.0.0"
+
+[libraries]
+guava = { module = "com.google.guava:guava", version.ref = "guava" }
+
+[plugins]
+kotlin-jvm = { id = "org.jetbrains.kotlin.jvm", version.ref = "kotlin" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle.kts"))
+	compareIgnoreLineBreaks(t, `
+		plugins {
+			alias(libs.plugins.kotlin.jvm)
+		}
+		implementation(libs.guava)
+	`, string(f))
+}
+
+func TestDiscoverModulesFromSettings(t *testing.T) {
+	parent := t.TempDir()
+	tempdir := filepath.Join(parent, "root")
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `
+		rootProject.name = "root"
+		include(":app", ":very:deep:nested:module")
+		// include(":commented-out")
+		include(":renamed")
+		project(":renamed").projectDir = file("modules/renamed-dir")
+		includeFlat("sibling")
+	`)
+	writeFile(t, tempdir, "build.gradle.kts", `
+		apply(from = "gradle/deps.gradle")
+	`)
+	writeFile(t, tempdir, "gradle/deps.gradle", `
+		implementation "applied:applied:1.0"
+	`)
+	writeFile(t, tempdir, "app/build.gradle.kts", `
+		implementation("app:app:1.0")
+	`)
+	writeFile(t, tempdir, "very/deep/nested/module/build.gradle.kts", `
+		implementation("deep:deep:1.0")
+	`)
+	writeFile(t, tempdir, "modules/renamed-dir/build.gradle.kts", `
+		implementation("renamed:renamed:1.0")
+	`)
+	writeFile(t, parent, "sibling/build.gradle.kts", `
+		implementation("sibling:sibling:1.0")
+	`)
+	writeFile(t, tempdir, "commented-out/build.gradle.kts", `
+		implementation("commented:commented:1.0")
+	`)
+	writeFile(t, tempdir, "app/src/test/resources/fixture/build.gradle.kts", `
+		implementation("fixture:fixture:1.0")
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+app-app = { group = "app", name = "app", version = "1.0" }
+applied-applied = { group = "applied", name = "applied", version = "1.0" }
+deep-deep = { group = "deep", name = "deep", version = "1.0" }
+renamed-renamed = { group = "renamed", name = "renamed", version = "1.0" }
+sibling-sibling = { group = "sibling", name = "sibling", version = "1.0" }
+`, string(f))
+}
+
+func TestIncludeFlatFromRelativePath(t *testing.T) {
+	parent := t.TempDir()
+	tempdir := filepath.Join(parent, "root")
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `includeFlat("sibling")`)
+	writeFile(t, parent, "sibling/build.gradle.kts", `
+		implementation("sibling:sibling:1.0")
+	`)
+	t.Chdir(tempdir)
+
+	os.Args = []string{"cli", "generate", ".", "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+sibling-sibling = { group = "sibling", name = "sibling", version = "1.0" }
+`, string(f))
+	f, _ = os.ReadFile(filepath.Join(parent, "sibling", "build.gradle.kts"))
+	compareIgnoreLineBreaks(t, `
+		implementation(libs.sibling.sibling)
+	`, string(f))
+}
+
+func TestSkipOutputDirectoriesInWalk(t *testing.T) {
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "build.gradle", `
+		implementation("root:root:1.0")
+	`)
+	writeFile(t, tempdir, "build/tmp/build.gradle", `
+		implementation("output:output:1.0")
+	`)
+	writeFile(t, tempdir, "node_modules/foo/build.gradle", `
+		implementation("npm:npm:1.0")
+	`)
+	writeFile(t, tempdir, "src/test/resources/build.gradle", `
+		implementation("fixture:fixture:1.0")
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--max-depth=3"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+root-root = { group = "root", name = "root", version = "1.0" }
+`, string(f))
+}
+
+func TestIncludeExcludePatterns(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle", `
+		include ':app', ':samples:demo', ':lib'
+	`)
+	writeFile(t, tempdir, "app/build.gradle", `
+		implementation "app:app:1.0"
+	`)
+	writeFile(t, tempdir, "lib/build.gradle", `
+		implementation "lib:lib:1.0"
+	`)
+	writeFile(t, tempdir, "samples/demo/build.gradle", `
+		implementation "demo:demo:1.0"
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--include=app/**,samples/**", "--exclude=**/demo/*"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+app-app = { group = "app", name = "app", version = "1.0" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "lib/build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		implementation "lib:lib:1.0"
+	`, string(f))
+}
+
+func TestRefuseDirtyFiles(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "build.gradle", `
+		implementation "com.example:lib:1.0"
+	`)
+	gitCommitAll(t, tempdir)
+	writeFile(t, tempdir, "build.gradle", `
+		implementation "com.example:lib:1.1"
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	err := generateCommand.Execute()
+	assert.ErrorContains(t, err, "uncommitted changes")
+	_, statErr := os.Stat(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	assert.True(t, os.IsNotExist(statErr))
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--allow-dirty"}
+	assert.NoError(t, generateCommand.Execute())
+	f, _ := os.ReadFile(filepath.Join(tempdir, "build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		implementation libs.com.example.lib
+	`, string(f))
+}
+
+func TestRefuseWorkTreeWithoutGit(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, ".git/HEAD", "ref: refs/heads/main\n")
+	writeFile(t, tempdir, "build.gradle", `
+		implementation "com.example:lib:1.0"
+	`)
+	t.Setenv("PATH", "")
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	err := generateCommand.Execute()
+	assert.ErrorContains(t, err, "git is not installed, so uncommitted changes cannot be detected")
+	_, statErr := os.Stat(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	assert.True(t, os.IsNotExist(statErr))
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--allow-dirty"}
+	assert.NoError(t, generateCommand.Execute())
+	f, _ := os.ReadFile(filepath.Join(tempdir, "build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		implementation libs.com.example.lib
+	`, string(f))
+}
+
+func TestSkipGitIgnoredFiles(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, ".gitignore", "generated/\n")
+	writeFile(t, tempdir, "build.gradle", `
+		implementation "com.example:lib:1.0"
+	`)
+	writeFile(t, tempdir, "generated/build.gradle", `
+		implementation "com.example:generated:1.0"
+	`)
+	gitCommitAll(t, tempdir)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+com-example-lib = { group = "com.example", name = "lib", version = "1.0" }
+`, string(f))
+}
+
+func TestCommitMigration(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "build.gradle", `
+		implementation "com.example:lib:1.0"
+	`)
+	writeFile(t, tempdir, "notes.txt", "draft")
+	gitCommitAll(t, tempdir)
+	writeFile(t, tempdir, "notes.txt", "still a draft")
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--commit"}
+	assert.NoError(t, generateCommand.Execute())
+
+	output, err := exec.Command("git", "-C", tempdir, "show", "--name-only", "--format=%B", "HEAD").CombinedOutput()
+	assert.NoError(t, err)
+	compareIgnoreLineBreaks(t, `Migrate dependencies to the Gradle version catalog
+
+Cataloged 0 versions, 1 libraries and 0 plugins.
+
+Updated files:
+- build.gradle
+- gradle/libs.versions.toml
+
+
+build.gradle
+gradle/libs.versions.toml
+`, string(output))
+
+	output, err = exec.Command("git", "-C", tempdir, "status", "--porcelain").CombinedOutput()
+	assert.NoError(t, err)
+	assert.Equal(t, " M notes.txt\n", string(output))
+}
+
+func TestMigrateSelectedModules(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle", `
+		include ':app', ':core:data', ':core:ui', ':feature'
+	`)
+	writeFile(t, tempdir, "app/build.gradle", `
+		implementation "com.example:app:1.0"
+	`)
+	writeFile(t, tempdir, "core/data/build.gradle", `
+		implementation "com.example:data:1.0"
+	`)
+	writeFile(t, tempdir, "core/ui/build.gradle", `
+		implementation "com.example:ui:1.0"
+	`)
+	writeFile(t, tempdir, "feature/build.gradle", `
+		def featureVersion = "2.0"
+		implementation "com.example:feature:$featureVersion"
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--module=:app,:core:*"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+featureVersion = "2.0"
+
+[libraries]
+com-example-app = { group = "com.example", name = "app", version = "1.0" }
+com-example-data = { group = "com.example", name = "data", version = "1.0" }
+com-example-feature = { group = "com.example", name = "feature", version.ref = "featureVersion" }
+com-example-ui = { group = "com.example", name = "ui", version = "1.0" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "core/ui/build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		implementation libs.com.example.ui
+	`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "feature/build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		def featureVersion = "2.0"
+		implementation "com.example:feature:$featureVersion"
+	`, string(f))
+}
+
+func TestMigrateFilesChangedSince(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle", `
+		include ':app', ':lib'
+	`)
+	writeFile(t, tempdir, "app/build.gradle", `
+		implementation "com.example:app:1.0"
+	`)
+	writeFile(t, tempdir, "lib/build.gradle", `
+		implementation "com.example:lib:1.0"
+	`)
+	gitCommitAll(t, tempdir)
+	writeFile(t, tempdir, "lib/build.gradle", `
+		implementation "com.example:lib:1.1"
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--allow-dirty", "--since=HEAD"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "app/build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		implementation "com.example:app:1.0"
+	`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "lib/build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		implementation libs.com.example.lib
+	`, string(f))
+}
+
+func TestCustomCatalogNameAndPath(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `rootProject.name = "sample"
+`)
+	writeFile(t, tempdir, "build.gradle.kts", `
+		plugins {
+			id("org.example.tool") version "1.0"
+		}
+		dependencies {
+			implementation("com.example:lib:2.0")
+		}
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--catalog-name=deps", "--catalog-path=catalogs/deps.toml"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "catalogs", "deps.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+com-example-lib = { group = "com.example", name = "lib", version = "2.0" }
+
+[plugins]
+org-example-tool = { id = "org.example.tool", version = "1.0" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle.kts"))
+	compareIgnoreLineBreaks(t, `
+		plugins {
+			alias(deps.plugins.org.example.tool)
+		}
+		dependencies {
+			implementation(deps.com.example.lib)
+		}
+	`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "settings.gradle.kts"))
+	compareIgnoreLineBreaks(t, `rootProject.name = "sample"
+dependencyResolutionManagement {
+    versionCatalogs {
+        create("deps") {
+            from(files("catalogs/deps.toml"))
+        }
+    }
+}
+`, string(f))
+}
+
+func TestRejectInvalidCatalogName(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--catalog-name=test-libs"}
+	assert.ErrorContains(t, generateCommand.Execute(), `invalid catalog name "test-libs"`)
+}
+
+func TestRouteIntoSeveralCatalogs(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "build.gradle.kts", `
+		plugins {
+			id("com.diffplug.spotless") version "6.25.0"
+		}
+		val junitVersion = "5.10.0"
+		dependencies {
+			implementation("com.google.guava:guava:33.0.0-jre")
+			testImplementation("com.google.guava:guava-testlib:33.0.0-jre")
+			testImplementation("org.junit.jupiter:junit-jupiter:$junitVersion")
+			testRuntimeOnly("org.junit.platform:junit-platform-launcher:1.10.0")
+			implementation("com.squareup.okio:okio:3.9.0")
+			testImplementation("com.squareup.okio:okio:3.9.0")
+		}
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--route=kind:plugin=tools,config:test*=testLibs"}
+	stdout, err := CaptureStdout(t, generateCommand.Execute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(stdout, "Updated: "+filepath.Join(tempdir, "settings.gradle.kts")))
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+com-google-guava-guava = { group = "com.google.guava", name = "guava", version = "33.0.0-jre" }
+com-squareup-okio-okio = { group = "com.squareup.okio", name = "okio", version = "3.9.0" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "gradle", "testLibs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+junitVersion = "5.10.0"
+
+[libraries]
+com-google-guava-guava-testlib = { group = "com.google.guava", name = "guava-testlib", version = "33.0.0-jre" }
+org-junit-jupiter-junit-jupiter = { group = "org.junit.jupiter", name = "junit-jupiter", version.ref = "junitVersion" }
+org-junit-platform-junit-platform-launcher = { group = "org.junit.platform", name = "junit-platform-launcher", version = "1.10.0" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "gradle", "tools.versions.toml"))
+	compareIgnoreLineBreaks(t, `[plugins]
+com-diffplug-spotless = { id = "com.diffplug.spotless", version = "6.25.0" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle.kts"))
+	compareIgnoreLineBreaks(t, `
+		plugins {
+			alias(tools.plugins.com.diffplug.spotless)
+		}
+		dependencies {
+			implementation(libs.com.google.guava.guava)
+			testImplementation(testLibs.com.google.guava.guava.testlib)
+			testImplementation(testLibs.org.junit.jupiter.junit.jupiter)
+			testRuntimeOnly(testLibs.org.junit.platform.junit.platform.launcher)
+			implementation(libs.com.squareup.okio.okio)
+			testImplementation(libs.com.squareup.okio.okio)
+		}
+	`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "settings.gradle.kts"))
+	compareIgnoreLineBreaks(t, `dependencyResolutionManagement {
+    versionCatalogs {
+        create("testLibs") {
+            from(files("gradle/testLibs.versions.toml"))
+        }
+        create("tools") {
+            from(files("gradle/tools.versions.toml"))
+        }
+    }
+}
+`, string(f))
+}
+
+func TestRouteIntoExistingVersionCatalogsBlock(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle", `rootProject.name = 'sample'
+dependencyResolutionManagement {
+    repositories {
+        mavenCentral()
+    }
+    versionCatalogs {
+        published {
+            from('com.example:catalog:1.0')
+        }
+    }
+}
+`)
+	writeFile(t, tempdir, "build.gradle", `
+		plugins {
+			id 'com.diffplug.spotless' version '6.25.0'
+		}
+		dependencies {
+			implementation 'com.google.guava:guava:33.0.0-jre'
+			testImplementation 'org.junit.jupiter:junit-jupiter:5.10.0'
+		}
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--route=kind:plugin=tools,config:test*=testLibs"}
+	stdout, err := CaptureStdout(t, generateCommand.Execute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(stdout, "Updated: "+filepath.Join(tempdir, "settings.gradle")))
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "settings.gradle"))
+	assert.Equal(t, `rootProject.name = 'sample'
+dependencyResolutionManagement {
+    repositories {
+        mavenCentral()
+    }
+    versionCatalogs {
+        published {
+            from('com.example:catalog:1.0')
+        }
+        testLibs {
+            from(files('gradle/testLibs.versions.toml'))
+        }
+        tools {
+            from(files('gradle/tools.versions.toml'))
+        }
+    }
+}
+`, string(f))
+}
+
+func TestRejectInvalidRoute(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--route=configuration:test*=testLibs"}
+	assert.ErrorContains(t, generateCommand.Execute(), `invalid route "configuration:test*=testLibs"`)
+}
+
+func TestSplitExistingCatalog(t *testing.T) {
+	resetFlagsOnCleanup(t, splitCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/libs.versions.toml", `[versions]
+junit = "5.10.0"
+kotlin = "2.0.0"
+
+[libraries]
+junit-jupiter = { module = "org.junit.jupiter:junit-jupiter", version.ref = "junit" }
+kotlin-stdlib = { module = "org.jetbrains.kotlin:kotlin-stdlib", version.ref = "kotlin" }
+kotlin-test = { module = "org.jetbrains.kotlin:kotlin-test", version.ref = "kotlin" }
+
+[plugins]
+kotlin-jvm = { id = "org.jetbrains.kotlin.jvm", version.ref = "kotlin" }
+`)
+	writeFile(t, tempdir, "settings.gradle", `rootProject.name = 'sample'
+`)
+	writeFile(t, tempdir, "build.gradle", `
+		plugins {
+			alias(libs.plugins.kotlin.jvm)
+		}
+		dependencies {
+			implementation libs.kotlin.stdlib
+			testImplementation libs.kotlin.test
+			testImplementation(platform(libs.junit.jupiter))
+		}
+		println(libs.versions.junit.get())
+	`)
+
+	os.Args = []string{"cli", "split", tempdir, "--route=config:test*=testLibs"}
+	assert.NoError(t, rootCmd.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+kotlin = "2.0.0"
+
+[libraries]
+kotlin-stdlib = { module = "org.jetbrains.kotlin:kotlin-stdlib", version.ref = "kotlin" }
+
+[plugins]
+kotlin-jvm = { id = "org.jetbrains.kotlin.jvm", version.ref = "kotlin" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "gradle", "testLibs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+junit = "5.10.0"
+kotlin = "2.0.0"
+
+[libraries]
+junit-jupiter = { module = "org.junit.jupiter:junit-jupiter", version.ref = "junit" }
+kotlin-test = { module = "org.jetbrains.kotlin:kotlin-test", version.ref = "kotlin" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		plugins {
+			alias(libs.plugins.kotlin.jvm)
+		}
+		dependencies {
+			implementation libs.kotlin.stdlib
+			testImplementation testLibs.kotlin.test
+			testImplementation(platform(testLibs.junit.jupiter))
+		}
+		println(testLibs.versions.junit.get())
+	`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "settings.gradle"))
+	compareIgnoreLineBreaks(t, `rootProject.name = 'sample'
+dependencyResolutionManagement {
+    versionCatalogs {
+        testLibs {
+            from(files('gradle/testLibs.versions.toml'))
+        }
+    }
+}
+`, string(f))
+}
+
+func TestTargetCatalogDeclaredInSettings(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	settings := `dependencyResolutionManagement {
+    versionCatalogs {
+        create("libs") {
+            from(files("deps/libs.toml"))
+        }
+    }
+}
+`
+	writeFile(t, tempdir, "settings.gradle.kts", settings)
+	writeFile(t, tempdir, "build.gradle.kts", `
+		implementation("com.example:lib:1.0")
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "deps", "libs.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+com-example-lib = { group = "com.example", name = "lib", version = "1.0" }
+`, string(f))
+	_, err := os.Stat(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	assert.True(t, os.IsNotExist(err))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "settings.gradle.kts"))
+	assert.Equal(t, settings, string(f))
+}
+
+func TestDefaultLibrariesExtensionName(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle", `dependencyResolutionManagement {
+    defaultLibrariesExtensionName = 'deps'
+}
+`)
+	writeFile(t, tempdir, "build.gradle", `
+		implementation 'com.example:lib:1.0'
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+com-example-lib = { group = "com.example", name = "lib", version = "1.0" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		implementation deps.com.example.lib
+	`, string(f))
+}
+
+func TestReuseEntriesOfOtherDeclaredCatalogs(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle", `dependencyResolutionManagement {
+    versionCatalogs {
+        testLibs {
+            from(files('gradle/test-libs.toml'))
+        }
+    }
+}
+`)
+	writeFile(t, tempdir, "gradle/test-libs.toml", `[versions]
+junit = "5.10.0"
+
+[libraries]
+junit = { module = "org.junit.jupiter:junit-jupiter", version.ref = "junit" }
+mockk = { module = "io.mockk:mockk", version = "1.13.0" }
+`)
+	writeFile(t, tempdir, "build.gradle", `
+		implementation 'com.google.guava:guava:33.0.0-jre'
+		testImplementation 'org.junit.jupiter:junit-jupiter:5.11.0'
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+com-google-guava-guava = { group = "com.google.guava", name = "guava", version = "33.0.0-jre" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "gradle", "test-libs.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+junit = "5.11.0"
+
+[libraries]
+junit = { module = "org.junit.jupiter:junit-jupiter", version.ref = "junit" }
+mockk = { module = "io.mockk:mockk", version = "1.13.0" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		implementation libs.com.google.guava.guava
+		testImplementation testLibs.junit
+	`, string(f))
+}
+
+func TestRegisterCatalogInIncludedBuilds(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `
+		include(":app")
+		includeBuild("build-logic")
+	`)
+	writeFile(t, tempdir, "app/build.gradle.kts", `
+		implementation("com.example:app:1.0")
+	`)
+	writeFile(t, tempdir, "build-logic/settings.gradle.kts", `includeBuild("conventions")
+`)
+	writeFile(t, tempdir, "build-logic/build.gradle.kts", `
+		implementation("com.example:logic:1.0")
+	`)
+	writeFile(t, tempdir, "build-logic/conventions/build.gradle", `
+		implementation 'com.example:conventions:1.0'
+	`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "build-logic", "settings.gradle.kts"))
+	compareIgnoreLineBreaks(t, `includeBuild("conventions")
+dependencyResolutionManagement {
+    versionCatalogs {
+        create("libs") {
+            from(files("../gradle/libs.versions.toml"))
+        }
+    }
+}
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build-logic", "conventions", "settings.gradle"))
+	compareIgnoreLineBreaks(t, `dependencyResolutionManagement {
+    versionCatalogs {
+        libs {
+            from(files('../../gradle/libs.versions.toml'))
+        }
+    }
+}
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build-logic", "conventions", "build.gradle"))
+	compareIgnoreLineBreaks(t, `
+		implementation libs.com.example.conventions
+	`, string(f))
+}
+
+func TestRewritePrecompiledScriptPluginsThroughCatalogAPI(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `include(":app")`)
+	writeFile(t, tempdir, "app/build.gradle.kts", `
+		implementation("com.example:app:1.0")
+	`)
+	writeFile(t, tempdir, "buildSrc/build.gradle.kts", `plugins {
+    `+"`kotlin-dsl`"+`
+}
+`)
+	writeFile(t, tempdir, "buildSrc/src/main/kotlin/conventions.gradle.kts", `plugins {
+    id("org.jetbrains.kotlin.jvm")
+}
+dependencies {
+    implementation("com.example:logic:1.0")
+}
+`)
+	writeFile(t, tempdir, "buildSrc/src/main/groovy/groovy-conventions.gradle", `dependencies {
+    implementation 'com.example:groovy:1.0'
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "buildSrc", "src", "main", "kotlin", "conventions.gradle.kts"))
+	compareIgnoreLineBreaks(t, `plugins {
+    id("org.jetbrains.kotlin.jvm")
+}
+dependencies {
+    implementation(versionCatalogs.named("libs").findLibrary("com-example-logic").get())
+}
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "buildSrc", "src", "main", "groovy", "groovy-conventions.gradle"))
+	compareIgnoreLineBreaks(t, `dependencies {
+    implementation versionCatalogs.named('libs').findLibrary('com-example-groovy').get()
+}
+`, string(f))
+}
+
+func TestRewritePrecompiledScriptPluginsThroughLibrariesFor(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `include(":app")`)
+	writeFile(t, tempdir, "app/build.gradle.kts", `
+		implementation("com.example:app:1.0")
+	`)
+	writeFile(t, tempdir, "buildSrc/build.gradle.kts", `plugins {
+    `+"`kotlin-dsl`"+`
+}
+dependencies {
+}
+`)
+	writeFile(t, tempdir, "buildSrc/src/main/kotlin/conventions.gradle.kts", `plugins {
+    id("org.jetbrains.kotlin.jvm")
+}
+dependencies {
+    implementation("com.example:logic:1.0")
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--precompiled-style=libraries-for"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "buildSrc", "src", "main", "kotlin", "conventions.gradle.kts"))
+	compareIgnoreLineBreaks(t, `plugins {
+    id("org.jetbrains.kotlin.jvm")
+}
+
+val libs = the<org.gradle.accessors.dm.LibrariesForLibs>()
+dependencies {
+    implementation(libs.com.example.logic)
+}
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "buildSrc", "build.gradle.kts"))
+	compareIgnoreLineBreaks(t, `plugins {
+    `+"`kotlin-dsl`"+`
+}
+dependencies {
+    implementation(files(libs.javaClass.superclass.protectionDomain.codeSource.location))
+}
+`, string(f))
+}
+
+func TestRejectUnknownPrecompiledStyle(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--precompiled-style=accessors"}
+	assert.ErrorContains(t, generateCommand.Execute(), "unknown precompiled script plugin style")
+}
+
+func TestMigrateBuildSrcDependencyObjects(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `include(":app")`)
+	writeFile(t, tempdir, "buildSrc/build.gradle.kts", `plugins {
+    `+"`kotlin-dsl`"+`
+}
+`)
+	writeFile(t, tempdir, "buildSrc/src/main/kotlin/Dependencies.kt", `object Versions {
+    const val okhttp = "4.12.0"
+    const val jvmTarget = "17"
+}
+
+object Libs {
+    const val okhttp = "com.squareup.okhttp3:okhttp:${Versions.okhttp}"
+    const val okhttpLogging = "com.squareup.okhttp3:logging-interceptor:${Versions.okhttp}"
+
+    object AndroidX {
+        const val coreKtx = "androidx.core:core-ktx:1.13.1"
+    }
+}
+`)
+	writeFile(t, tempdir, "app/build.gradle.kts", `kotlin {
+    jvmToolchain(Versions.jvmTarget.toInt())
+}
+dependencies {
+    implementation(Libs.okhttp)
+    implementation(Libs.okhttpLogging)
+    implementation(Libs.AndroidX.coreKtx)
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+jvmTarget = "17"
+okhttp = "4.12.0"
+
+[libraries]
+androidX-coreKtx = { group = "androidx.core", name = "core-ktx", version = "1.13.1" }
+okhttp = { group = "com.squareup.okhttp3", name = "okhttp", version.ref = "okhttp" }
+okhttpLogging = { group = "com.squareup.okhttp3", name = "logging-interceptor", version.ref = "okhttp" }
+
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "app", "build.gradle.kts"))
+	compareIgnoreLineBreaks(t, `kotlin {
+    jvmToolchain(libs.versions.jvmTarget.get().toInt())
+}
+dependencies {
+    implementation(libs.okhttp)
+    implementation(libs.okhttpLogging)
+    implementation(libs.androidX.coreKtx)
+}
+`, string(f))
+
+	assert.NoFileExists(t, filepath.Join(tempdir, "buildSrc", "src", "main", "kotlin", "Dependencies.kt"))
+}
+
+func TestDeprecateBuildSrcDependencyObjectsStillInUse(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `include(":app")`)
+	writeFile(t, tempdir, "buildSrc/build.gradle.kts", `plugins {
+    `+"`kotlin-dsl`"+`
+}
+`)
+	writeFile(t, tempdir, "buildSrc/src/main/kotlin/Dependencies.kt", `object Libs {
+    private const val okhttpVersion = "4.12.0"
+    const val okhttp = "com.squareup.okhttp3:okhttp:$okhttpVersion"
+    const val detektPlugin = "io.gitlab.arturbosch.detekt"
+}
+`)
+	writeFile(t, tempdir, "buildSrc/src/main/kotlin/Conventions.kt", `fun okhttp() = Libs.okhttp
+`)
+	writeFile(t, tempdir, "app/build.gradle.kts", `dependencies {
+    implementation(Libs.okhttp)
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "app", "build.gradle.kts"))
+	compareIgnoreLineBreaks(t, `dependencies {
+    implementation(libs.okhttp)
+}
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "buildSrc", "src", "main", "kotlin", "Dependencies.kt"))
+	compareIgnoreLineBreaks(t, `object Libs {
+    @Deprecated("Use libs.versions.okhttpVersion from the version catalog")
+    private const val okhttpVersion = "4.12.0"
+    @Deprecated("Use libs.okhttp from the version catalog")
+    const val okhttp = "com.squareup.okhttp3:okhttp:$okhttpVersion"
+    const val detektPlugin = "io.gitlab.arturbosch.detekt"
+}
+`, string(f))
+}
+
+func TestMigrateExtDependencyMaps(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle", `include ':app'`)
+	writeFile(t, tempdir, "build.gradle", `apply from: 'dependencies.gradle'
+
+allprojects {
+    group = 'com.example'
+}
+`)
+	writeFile(t, tempdir, "dependencies.gradle", `// shared dependencies
+ext.versions = [
+    okhttp: '4.12.0',
+]
+
+ext.deps = [
+    okhttp: "com.squareup.okhttp3:okhttp:$versions.okhttp",
+    'okhttp-logging': "com.squareup.okhttp3:logging-interceptor:${versions.okhttp}",
+    androidx: [
+        core: "androidx.core:core-ktx:1.13.1",
+    ],
+]
+`)
+	writeFile(t, tempdir, "app/build.gradle", `dependencies {
+    implementation deps.okhttp
+    implementation deps.'okhttp-logging'
+    implementation rootProject.ext.deps.androidx.core
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+okhttp = "4.12.0"
+
+[libraries]
+androidx-core = { group = "androidx.core", name = "core-ktx", version = "1.13.1" }
+okhttp = { group = "com.squareup.okhttp3", name = "okhttp", version.ref = "okhttp" }
+okhttp-logging = { group = "com.squareup.okhttp3", name = "logging-interceptor", version.ref = "okhttp" }
+
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "app", "build.gradle"))
+	compareIgnoreLineBreaks(t, `dependencies {
+    implementation libs.okhttp
+    implementation libs.okhttp.logging
+    implementation libs.androidx.core
+}
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle"))
+	compareIgnoreLineBreaks(t, `
+allprojects {
+    group = 'com.example'
+}
+`, string(f))
+	assert.NoFileExists(t, filepath.Join(tempdir, "dependencies.gradle"))
+}
+
+func TestKeepExtDependencyMapsStillInUse(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle", `include ':app'`)
+	writeFile(t, tempdir, "build.gradle", `ext {
+    versions = [
+        okhttp: '4.12.0',
+        minSdk: 21,
+    ]
+    deps = [
+        okhttp: "com.squareup.okhttp3:okhttp:${versions.okhttp}",
+    ]
+}
+`)
+	writeFile(t, tempdir, "app/build.gradle", `android {
+    defaultConfig {
+        minSdk versions.minSdk
+    }
+}
+dependencies {
+    implementation deps['okhttp']
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "app", "build.gradle"))
+	compareIgnoreLineBreaks(t, `android {
+    defaultConfig {
+        minSdk versions.minSdk
+    }
+}
+dependencies {
+    implementation libs.okhttp
+}
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle"))
+	compareIgnoreLineBreaks(t, `ext {
+    versions = [
+        okhttp: '4.12.0',
+        minSdk: 21,
+    ]
+}
+`, string(f))
+}
+
+func TestResolveVariablesThroughPropertyFiles(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle", `include ':app', ':lib'`)
+	writeFile(t, tempdir, "gradle.properties", `okhttpVersion: 4.11.0
+guavaVersion = 33.0.\
+    0-jre
+`)
+	writeFile(t, tempdir, "app/gradle.properties", `# app uses a newer okhttp
+okhttpVersion=4.12.0
+`)
+	writeFile(t, tempdir, "app/build.gradle", `dependencies {
+    implementation "com.squareup.okhttp3:okhttp:$okhttpVersion"
+    implementation "com.google.guava:guava:$guavaVersion"
+}
+`)
+	writeFile(t, tempdir, "lib/build.gradle", `dependencies {
+    implementation "org.slf4j:slf4j-api:$slf4jVersion"
+}
+`)
+	writeFile(t, tempdir, "versions.properties", `slf4jVersion 2.0.13
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--properties-file", "versions.properties"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+guavaVersion = "33.0.0-jre"
+okhttpVersion = "4.12.0"
+slf4jVersion = "2.0.13"
+
+[libraries]
+com-google-guava-guava = { group = "com.google.guava", name = "guava", version.ref = "guavaVersion" }
+com-squareup-okhttp3-okhttp = { group = "com.squareup.okhttp3", name = "okhttp", version.ref = "okhttpVersion" }
+org-slf4j-slf4j-api = { group = "org.slf4j", name = "slf4j-api", version.ref = "slf4jVersion" }
+
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "gradle.properties"))
+	assert.Equal(t, `okhttpVersion: 4.11.0
+`, string(f))
+	f, _ = os.ReadFile(filepath.Join(tempdir, "app", "gradle.properties"))
+	assert.Equal(t, `# app uses a newer okhttp
+`, string(f))
+	f, _ = os.ReadFile(filepath.Join(tempdir, "versions.properties"))
+	assert.Equal(t, ``, string(f))
+}
+
+func TestResolveVariablesThroughGradleScopes(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `include(":app", ":lib")`)
+	writeFile(t, tempdir, "gradle.properties", `coroutinesVersion=1.8.1
+serialization=1.6.3
+`)
+	writeFile(t, tempdir, "build.gradle.kts", `val major = "2"
+extra["retrofitVersion"] = "$major.9.0"
+`)
+	writeFile(t, tempdir, "app/build.gradle.kts", `val okhttpVersion = "4.12.0"
+val coroutinesVersion: String by project
+val serializationVersion = project.property("serialization") as String
+dependencies {
+    implementation("com.squareup.okhttp3:okhttp:$okhttpVersion")
+    implementation("com.squareup.retrofit2:retrofit:$retrofitVersion")
+    implementation("org.jetbrains.kotlinx:kotlinx-coroutines-core:$coroutinesVersion")
+    implementation("org.jetbrains.kotlinx:kotlinx-serialization-json:$serializationVersion")
+    implementation("com.example:missing:$missingVersion")
+}
+`)
+	writeFile(t, tempdir, "lib/build.gradle", `ext.okhttpVersion = '4.11.0'
+dependencies {
+    implementation "com.squareup.okhttp3:logging-interceptor:$okhttpVersion"
+    implementation "com.squareup.retrofit2:converter-gson:${rootProject.ext.retrofitVersion}"
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	stdout, err := CaptureStdout(t, generateCommand.Execute)
+	assert.NoError(t, err)
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+coroutinesVersion = "1.8.1"
+okhttpVersion = "4.12.0"
+okhttpVersion-lib = "4.11.0"
+retrofitVersion = "2.9.0"
+serialization = "1.6.3"
+
+[libraries]
+com-squareup-okhttp3-logging-interceptor = { group = "com.squareup.okhttp3", name = "logging-interceptor", version.ref = "okhttpVersion-lib" }
+com-squareup-okhttp3-okhttp = { group = "com.squareup.okhttp3", name = "okhttp", version.ref = "okhttpVersion" }
+com-squareup-retrofit2-converter-gson = { group = "com.squareup.retrofit2", name = "converter-gson", version.ref = "retrofitVersion" }
+com-squareup-retrofit2-retrofit = { group = "com.squareup.retrofit2", name = "retrofit", version.ref = "retrofitVersion" }
+org-jetbrains-kotlinx-kotlinx-coroutines-core = { group = "org.jetbrains.kotlinx", name = "kotlinx-coroutines-core", version.ref = "coroutinesVersion" }
+org-jetbrains-kotlinx-kotlinx-serialization-json = { group = "org.jetbrains.kotlinx", name = "kotlinx-serialization-json", version.ref = "serialization" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "app", "build.gradle.kts"))
+	assert.Equal(t, `val coroutinesVersion: String by project
+val serializationVersion = project.property("serialization") as String
+dependencies {
+    implementation(libs.com.squareup.okhttp3.okhttp)
+    implementation(libs.com.squareup.retrofit2.retrofit)
+    implementation(libs.org.jetbrains.kotlinx.kotlinx.coroutines.core)
+    implementation(libs.org.jetbrains.kotlinx.kotlinx.serialization.json)
+    implementation("com.example:missing:$missingVersion")
+}
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "lib", "build.gradle"))
+	assert.Equal(t, `dependencies {
+    implementation libs.com.squareup.okhttp3.logging.interceptor
+    implementation libs.com.squareup.retrofit2.converter.gson
+}
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "build.gradle.kts"))
+	assert.Equal(t, "", string(f))
+
+	assert.Contains(t, stdout, "Unresolved version variables:")
+	assert.Contains(t, stdout, filepath.Join(tempdir, "app", "build.gradle.kts")+":9: $missingVersion")
+}
+
+func TestCarryRichVersions(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `include(":app", ":lib")`)
+	writeFile(t, tempdir, "app/build.gradle.kts", `dependencies {
+    implementation("com.example:strict:1.5!!")
+    implementation("com.example:ranged:[1.0,2.0)!!1.5")
+    implementation("com.example:dynamic:1.+")
+    implementation("com.example:latest:latest.release")
+    implementation("com.example:block") {
+        version {
+            strictly("[1.0, 2.0[")
+            prefer("1.5")
+            reject("1.6", "1.7")
+        }
+    }
+    implementation("com.example:kept:1.0") {
+        version { rejectAll() }
+        because("pinned")
+    }
+    implementation("com.example:rejected") { version { rejectAll() } }
+    implementation("com.example:notation:1.0") {
+        version {
+            strictly("[1.0, 2.0[")
+            prefer("1.5")
+        }
+    }
+}
+`)
+	writeFile(t, tempdir, "lib/build.gradle", `dependencies {
+    implementation('com.example:groovy') { version { strictly '2.0' } }
+    implementation group: 'com.example', name: 'mapped', version: '3.+'
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+com-example-block = { group = "com.example", name = "block", version = { strictly = "[1.0, 2.0[", prefer = "1.5", reject = ["1.6", "1.7"] } }
+com-example-dynamic = { group = "com.example", name = "dynamic", version = { require = "1.+" } }
+com-example-groovy = { group = "com.example", name = "groovy", version = { strictly = "2.0" } }
+com-example-kept = { group = "com.example", name = "kept", version = { require = "1.0", rejectAll = true } }
+com-example-latest = { group = "com.example", name = "latest", version = { require = "latest.release" } }
+com-example-mapped = { group = "com.example", name = "mapped", version = { require = "3.+" } }
+com-example-notation = { group = "com.example", name = "notation", version = { strictly = "[1.0, 2.0[", prefer = "1.5" } }
+com-example-ranged = { group = "com.example", name = "ranged", version = { strictly = "[1.0,2.0)", prefer = "1.5" } }
+com-example-rejected = { group = "com.example", name = "rejected", version = { rejectAll = true } }
+com-example-strict = { group = "com.example", name = "strict", version = { strictly = "1.5" } }
+`, string(f))
+	_, err := ReadCatalog(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	assert.NoError(t, err)
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "app", "build.gradle.kts"))
+	assert.Equal(t, `dependencies {
+    implementation(libs.com.example.strict)
+    implementation(libs.com.example.ranged)
+    implementation(libs.com.example.dynamic)
+    implementation(libs.com.example.latest)
+    implementation(libs.com.example.block)
+    implementation(libs.com.example.kept) {
+        because("pinned")
+    }
+    implementation(libs.com.example.rejected)
+    implementation(libs.com.example.notation)
+}
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "lib", "build.gradle"))
+	assert.Equal(t, `dependencies {
+    implementation(libs.com.example.groovy)
+    implementation libs.com.example.mapped
+}
+`, string(f))
+}
+
+func TestKeepArtifactSelectors(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `include(":app", ":lib")`)
+	writeFile(t, tempdir, "app/build.gradle.kts", `dependencies {
+    implementation("com.example:widget:1.0@aar")
+    implementation("com.example:natives:1.0:linux@zip")
+    implementation("com.example:sdk:2.0@aar") {
+        isTransitive = true
+    }
+    implementation(group = "com.example", name = "tools", version = "3.0", classifier = "sources", ext = "jar")
+    runtimeOnly(group = "com.example", name = "server", version = "4.0", configuration = "runtime") {
+        because("the runtime variant")
+    }
+}
+`)
+	writeFile(t, tempdir, "lib/build.gradle", `dependencies {
+    implementation 'com.example:widget:1.0@aar'
+    implementation group: 'com.example', name: 'tools', version: '3.0', classifier: 'sources'
+    runtimeOnly group: 'com.example', name: 'server', version: '4.0', configuration: 'runtime'
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+com-example-natives = { group = "com.example", name = "natives", version = "1.0" }
+com-example-sdk = { group = "com.example", name = "sdk", version = "2.0" }
+com-example-server = { group = "com.example", name = "server", version = "4.0" }
+com-example-tools = { group = "com.example", name = "tools", version = "3.0" }
+com-example-widget = { group = "com.example", name = "widget", version = "1.0" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "app", "build.gradle.kts"))
+	assert.Equal(t, `dependencies {
+    implementation(variantOf(libs.com.example.widget) { artifactType("aar") }) { isTransitive = false }
+    implementation(variantOf(libs.com.example.natives) { classifier("linux"); artifactType("zip") }) { isTransitive = false }
+    implementation(variantOf(libs.com.example.sdk) { artifactType("aar") }) {
+        isTransitive = true
+    }
+    implementation(variantOf(libs.com.example.tools) { classifier("sources"); artifactType("jar") }) { isTransitive = false }
+    runtimeOnly(libs.com.example.server) { targetConfiguration = "runtime";
+        because("the runtime variant")
+    }
+}
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "lib", "build.gradle"))
+	assert.Equal(t, `dependencies {
+    implementation(variantOf(libs.com.example.widget) { artifactType('aar') }) { transitive = false }
+    implementation variantOf(libs.com.example.tools) { classifier('sources') }
+    runtimeOnly(libs.com.example.server) { targetConfiguration = 'runtime' }
+}
+`, string(f))
+}
+
+func TestMigrateConstraintsAndResolutionRules(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `include(":app", ":lib")`)
+	writeFile(t, tempdir, "app/build.gradle.kts", `dependencies {
+    implementation("com.fasterxml.jackson.core:jackson-databind")
+    constraints {
+        implementation("com.fasterxml.jackson.core:jackson-databind:2.17.1") {
+            because("CVE-2023-35116")
+        }
+    }
+}
+configurations.all {
+    resolutionStrategy {
+        force("com.google.guava:guava:33.2.0-jre", "org.slf4j:slf4j-api:2.0.13")
+        eachDependency {
+            if (requested.group == "org.jetbrains.kotlin") {
+                useVersion("1.9.24")
+                because("align Kotlin")
+            }
+        }
+        dependencySubstitution {
+            substitute(module("commons-logging:commons-logging")).using(module("org.slf4j:jcl-over-slf4j:2.0.13"))
+        }
+    }
+}
+`)
+	writeFile(t, tempdir, "lib/build.gradle", `configurations.all {
+    resolutionStrategy {
+        force 'com.google.guava:guava:33.2.0-jre', 'org.slf4j:slf4j-api:2.0.13'
+        eachDependency { details ->
+            if (details.requested.group == 'io.netty' && details.requested.name == 'netty-all') {
+                details.useVersion '4.1.110.Final'
+            }
+        }
+        dependencySubstitution {
+            substitute module('log4j:log4j') using module('org.slf4j:log4j-over-slf4j:2.0.13') because 'use slf4j'
+        }
+    }
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+io-netty-netty-all = "4.1.110.Final"
+org-jetbrains-kotlin = "1.9.24"
+org-slf4j-jcl-over-slf4j = "2.0.13"
+org-slf4j-log4j-over-slf4j = "2.0.13"
+
+[libraries]
+com-fasterxml-jackson-core-jackson-databind = { group = "com.fasterxml.jackson.core", name = "jackson-databind", version = "2.17.1" }
+com-google-guava-guava = { group = "com.google.guava", name = "guava", version = "33.2.0-jre" }
+org-slf4j-jcl-over-slf4j = { group = "org.slf4j", name = "jcl-over-slf4j", version.ref = "org-slf4j-jcl-over-slf4j" }
+org-slf4j-log4j-over-slf4j = { group = "org.slf4j", name = "log4j-over-slf4j", version.ref = "org-slf4j-log4j-over-slf4j" }
+org-slf4j-slf4j-api = { group = "org.slf4j", name = "slf4j-api", version = "2.0.13" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "app", "build.gradle.kts"))
+	assert.Equal(t, `dependencies {
+    implementation(libs.com.fasterxml.jackson.core.jackson.databind)
+    constraints {
+        implementation(libs.com.fasterxml.jackson.core.jackson.databind) {
+            because("CVE-2023-35116")
+        }
+    }
+}
+configurations.all {
+    resolutionStrategy {
+        force(libs.com.google.guava.guava, libs.org.slf4j.slf4j.api)
+        eachDependency {
+            if (requested.group == "org.jetbrains.kotlin") {
+                useVersion(libs.versions.org.jetbrains.kotlin.get())
+                because("align Kotlin")
+            }
+        }
+        dependencySubstitution {
+            substitute(module("commons-logging:commons-logging")).using(module("org.slf4j:jcl-over-slf4j:${libs.versions.org.slf4j.jcl.over.slf4j.get()}"))
+        }
+    }
+}
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "lib", "build.gradle"))
+	assert.Equal(t, `configurations.all {
+    resolutionStrategy {
+        force libs.com.google.guava.guava, libs.org.slf4j.slf4j.api
+        eachDependency { details ->
+            if (details.requested.group == 'io.netty' && details.requested.name == 'netty-all') {
+                details.useVersion libs.versions.io.netty.netty.all.get()
+            }
+        }
+        dependencySubstitution {
+            substitute module('log4j:log4j') using module("org.slf4j:log4j-over-slf4j:${libs.versions.org.slf4j.log4j.over.slf4j.get()}") because 'use slf4j'
+        }
+    }
+}
+`, string(f))
+}
+
+func TestMigrateDependencyManagement(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `include(":app", ":lib")`)
+	writeFile(t, tempdir, "app/build.gradle.kts", `dependencyManagement {
+    imports {
+        mavenBom("org.springframework.cloud:spring-cloud-dependencies:2023.0.2")
+    }
+    dependencies {
+        dependency("org.apache.commons:commons-lang3:3.14.0")
+        dependencySet("io.grpc:1.64.0") {
+            entry("grpc-netty")
+            entry("grpc-stub")
+        }
+    }
+}
+dependencies {
+    implementation("org.apache.commons:commons-lang3")
+}
+`)
+	writeFile(t, tempdir, "lib/build.gradle", `dependencyManagement {
+    imports {
+        mavenBom 'org.springframework.cloud:spring-cloud-dependencies:2023.0.2'
+    }
+    dependencies {
+        dependency 'com.google.guava:guava:33.2.0-jre'
+        dependencySet(group: 'org.slf4j', version: '2.0.13') {
+            entry 'slf4j-api'
+            entry 'jcl-over-slf4j'
+        }
+    }
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+com-google-guava-guava = "33.2.0-jre"
+io-grpc = "1.64.0"
+org-apache-commons-commons-lang3 = "3.14.0"
+org-slf4j = "2.0.13"
+org-springframework-cloud-spring-cloud-dependencies = "2023.0.2"
+
+[libraries]
+com-google-guava-guava = { group = "com.google.guava", name = "guava", version.ref = "com-google-guava-guava" }
+io-grpc-grpc-netty = { group = "io.grpc", name = "grpc-netty", version.ref = "io-grpc" }
+io-grpc-grpc-stub = { group = "io.grpc", name = "grpc-stub", version.ref = "io-grpc" }
+org-apache-commons-commons-lang3 = { group = "org.apache.commons", name = "commons-lang3", version.ref = "org-apache-commons-commons-lang3" }
+org-slf4j-jcl-over-slf4j = { group = "org.slf4j", name = "jcl-over-slf4j", version.ref = "org-slf4j" }
+org-slf4j-slf4j-api = { group = "org.slf4j", name = "slf4j-api", version.ref = "org-slf4j" }
+org-springframework-cloud-spring-cloud-dependencies = { group = "org.springframework.cloud", name = "spring-cloud-dependencies", version.ref = "org-springframework-cloud-spring-cloud-dependencies" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "app", "build.gradle.kts"))
+	assert.Equal(t, `dependencyManagement {
+    imports {
+        mavenBom("org.springframework.cloud:spring-cloud-dependencies:${libs.versions.org.springframework.cloud.spring.cloud.dependencies.get()}")
+    }
+    dependencies {
+        dependency("org.apache.commons:commons-lang3:${libs.versions.org.apache.commons.commons.lang3.get()}")
+        dependencySet("io.grpc:${libs.versions.io.grpc.get()}") {
+            entry("grpc-netty")
+            entry("grpc-stub")
+        }
+    }
+}
+dependencies {
+    implementation(libs.org.apache.commons.commons.lang3)
+}
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "lib", "build.gradle"))
+	assert.Equal(t, `dependencyManagement {
+    imports {
+        mavenBom "org.springframework.cloud:spring-cloud-dependencies:${libs.versions.org.springframework.cloud.spring.cloud.dependencies.get()}"
+    }
+    dependencies {
+        dependency "com.google.guava:guava:${libs.versions.com.google.guava.guava.get()}"
+        dependencySet(group: 'org.slf4j', version: libs.versions.org.slf4j.get()) {
+            entry 'slf4j-api'
+            entry 'jcl-over-slf4j'
+        }
+    }
+}
+`, string(f))
+}
+
+func TestConvertBuilderCatalogs(t *testing.T) {
+	resetFlagsOnCleanup(t, convertCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle.properties", "okhttpVersion=4.12.0")
+	writeFile(t, tempdir, "settings.gradle.kts", `val kotlinVersion = "2.0.0"
+dependencyResolutionManagement {
+    versionCatalogs {
+        create("libs") {
+            // the Kotlin toolchain
+            version("kotlin", kotlinVersion)
+            /* pinned until the
+               next release */
+            version("guava") {
+                strictly("[31,33[")
+                prefer("32.0")
+            }
+            library("okhttp-core", "com.squareup.okhttp3", "okhttp").version(okhttpVersion)
+            library("okhttp-logging", "com.squareup.okhttp3:logging-interceptor:4.12.0")
+            library("kotlin-stdlib", "org.jetbrains.kotlin", "kotlin-stdlib").versionRef("kotlin")
+            library("guava", "com.google.guava", "guava").version {
+                strictly("33.2.0-jre")
+            }
+            bundle("okhttp", listOf("okhttp-core", "okhttp-logging"))
+            plugin("kotlin-jvm", "org.jetbrains.kotlin.jvm").versionRef("kotlin")
+        }
+        create("testLibs") {
+            library("junit", "org.junit.jupiter", "junit-jupiter").withoutVersion()
+        }
+        create("published") {
+            from("com.example:catalog:1.0")
+        }
+    }
+}
+include(":app")
+`)
+
+	os.Args = []string{"cli", "convert", tempdir}
+	assert.NoError(t, rootCmd.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+guava = { strictly = "[31,33[", prefer = "32.0" }
+kotlin = "2.0.0"
+
+[libraries]
+guava = { group = "com.google.guava", name = "guava", version = { strictly = "33.2.0-jre" } }
+kotlin-stdlib = { group = "org.jetbrains.kotlin", name = "kotlin-stdlib", version.ref = "kotlin" }
+okhttp-core = { group = "com.squareup.okhttp3", name = "okhttp", version = "4.12.0" }
+okhttp-logging = { group = "com.squareup.okhttp3", name = "logging-interceptor", version = "4.12.0" }
+
+[bundles]
+okhttp = ["okhttp-core", "okhttp-logging"]
+
+[plugins]
+kotlin-jvm = { id = "org.jetbrains.kotlin.jvm", version.ref = "kotlin" }
+
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "gradle", "testLibs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+junit = { group = "org.junit.jupiter", name = "junit-jupiter" }
+
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "settings.gradle.kts"))
+	assert.Equal(t, `val kotlinVersion = "2.0.0"
+dependencyResolutionManagement {
+    versionCatalogs {
+        create("testLibs") {
+            from(files("gradle/testLibs.versions.toml"))
+        }
+        create("published") {
+            from("com.example:catalog:1.0")
+        }
+    }
+}
+include(":app")
+`, string(f))
+}
+
+func TestConvertBuilderCatalogsInGroovy(t *testing.T) {
+	resetFlagsOnCleanup(t, convertCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/deps.versions.toml", `[versions]
+kotlin = "2.0.0"
+`)
+	writeFile(t, tempdir, "settings.gradle", `dependencyResolutionManagement {
+    versionCatalogs {
+        deps {
+            from(files('gradle/deps.versions.toml'))
+            library('okhttp', 'com.squareup.okhttp3', 'okhttp').version('4.12.0') // networking
+            bundle('network', ['okhttp'])
+        }
+    }
+}
+`)
+
+	os.Args = []string{"cli", "convert", tempdir}
+	assert.NoError(t, rootCmd.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "deps.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+kotlin = "2.0.0"
+
+[libraries]
+okhttp = { group = "com.squareup.okhttp3", name = "okhttp", version = "4.12.0" }
+
+[bundles]
+network = ["okhttp"]
+
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "settings.gradle"))
+	assert.Equal(t, `dependencyResolutionManagement {
+    versionCatalogs {
+        deps {
+            from(files('gradle/deps.versions.toml'))
+        }
+    }
+}
+`, string(f))
+}
+
+func TestGenerateFromMaven(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "pom.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <modelVersion>4.0.0</modelVersion>
+  <groupId>com.example</groupId>
+  <artifactId>parent</artifactId>
+  <version>1.0.0-SNAPSHOT</version>
+  <packaging>pom</packaging>
+  <modules>
+    <module>app</module>
+    <module>lib</module>
+  </modules>
+  <properties>
+    <java.version>17</java.version>
+    <spring-boot.version>3.3.0</spring-boot.version>
+    <jackson.version>2.17.1</jackson.version>
+    <guava.version>33.2.0-jre</guava.version>
+  </properties>
+  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>org.springframework.boot</groupId>
+        <artifactId>spring-boot-dependencies</artifactId>
+        <version>${spring-boot.version}</version>
+        <type>pom</type>
+        <scope>import</scope>
+      </dependency>
+      <dependency>
+        <groupId>com.google.guava</groupId>
+        <artifactId>guava</artifactId>
+        <version>${guava.version}</version>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>
+  <build>
+    <pluginManagement>
+      <plugins>
+        <plugin>
+          <groupId>org.springframework.boot</groupId>
+          <artifactId>spring-boot-maven-plugin</artifactId>
+          <version>${spring-boot.version}</version>
+        </plugin>
+        <plugin>
+          <artifactId>maven-compiler-plugin</artifactId>
+          <version>3.13.0</version>
+        </plugin>
+      </plugins>
+    </pluginManagement>
+  </build>
+</project>
+`)
+	writeFile(t, tempdir, "app/pom.xml", `<project>
+  <parent>
+    <groupId>com.example</groupId>
+    <artifactId>parent</artifactId>
+    <version>1.0.0-SNAPSHOT</version>
+  </parent>
+  <artifactId>app</artifactId>
+  <dependencies>
+    <dependency>
+      <groupId>${project.groupId}</groupId>
+      <artifactId>lib</artifactId>
+      <version>${project.version}</version>
+    </dependency>
+    <dependency>
+      <groupId>com.google.guava</groupId>
+      <artifactId>guava</artifactId>
+    </dependency>
+    <dependency>
+      <groupId>com.fasterxml.jackson.core</groupId>
+      <artifactId>jackson-databind</artifactId>
+      <version>${jackson.version}</version>
+    </dependency>
+    <dependency>
+      <groupId>org.junit.jupiter</groupId>
+      <artifactId>junit-jupiter</artifactId>
+      <version>5.10.2</version>
+      <scope>test</scope>
+    </dependency>
+  </dependencies>
+</project>
+`)
+	writeFile(t, tempdir, "lib/pom.xml", `<project>
+  <parent>
+    <groupId>com.example</groupId>
+    <artifactId>parent</artifactId>
+    <version>1.0.0-SNAPSHOT</version>
+  </parent>
+  <artifactId>lib</artifactId>
+  <properties>
+    <jackson.version>2.16.0</jackson.version>
+  </properties>
+  <dependencies>
+    <dependency>
+      <groupId>com.fasterxml.jackson.core</groupId>
+      <artifactId>jackson-core</artifactId>
+      <version>${jackson.version}</version>
+    </dependency>
+    <dependency>
+      <groupId>org.slf4j</groupId>
+      <artifactId>slf4j-api</artifactId>
+      <version>${slf4j.version}</version>
+    </dependency>
+  </dependencies>
+</project>
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--from-maven", "--auto-latest=false"}
+	stdout, err := CaptureStdout(t, generateCommand.Execute)
+	assert.NoError(t, err)
+	assert.Contains(t, stdout, filepath.Join(tempdir, "lib", "pom.xml")+":20: $slf4j.version")
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+guava-version = "33.2.0-jre"
+jackson-version = "2.17.1"
+jackson-version-lib = "2.16.0"
+spring-boot-version = "3.3.0"
+
+[libraries]
+com-fasterxml-jackson-core-jackson-core = { group = "com.fasterxml.jackson.core", name = "jackson-core", version.ref = "jackson-version-lib" }
+com-fasterxml-jackson-core-jackson-databind = { group = "com.fasterxml.jackson.core", name = "jackson-databind", version.ref = "jackson-version" }
+com-google-guava-guava = { group = "com.google.guava", name = "guava", version.ref = "guava-version" }
+org-junit-jupiter-junit-jupiter = { group = "org.junit.jupiter", name = "junit-jupiter", version = "5.10.2" }
+org-springframework-boot-spring-boot-dependencies = { group = "org.springframework.boot", name = "spring-boot-dependencies", version.ref = "spring-boot-version" }
+
+[plugins]
+org-springframework-boot = { id = "org.springframework.boot", version.ref = "spring-boot-version" }
+`, string(f))
+}
+
+func TestMultiplatformSourceSets(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `include(":shared", ":legacy")`)
+	writeFile(t, tempdir, "shared/build.gradle.kts", `plugins {
+    kotlin("multiplatform")
+}
+kotlin {
+    jvm()
+    js { browser() }
+    sourceSets {
+        commonMain.dependencies {
+            implementation( "io.ktor:ktor-client-core:2.3.11" )
+            implementation(project(":core"))
+        }
+        commonTest.dependencies {
+            implementation(kotlin("test"))
+            implementation("org.jetbrains.kotlinx:kotlinx-coroutines-test:1.8.1")
+        }
+        val jvmTest by getting {
+            dependencies {
+                implementation("io.ktor:ktor-client-mock:2.3.11")
+            }
+        }
+        jsMain {
+            dependencies {
+                implementation(npm("left-pad", "1.3.0"))
+                implementation(devNpm("sass:loader", "13.0.0"))
+            }
+        }
+    }
+}
+dependencies {
+    jvmMainImplementation("com.squareup.okio:okio:3.9.0")
+}
+`)
+	writeFile(t, tempdir, "legacy/build.gradle", `plugins {
+    id 'org.jetbrains.kotlin.multiplatform'
+}
+kotlin {
+    sourceSets {
+        commonMain {
+            dependencies {
+                implementation 'io.ktor:ktor-client-core:2.3.11'
+            }
+        }
+    }
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false", "--route", "config:*Test*=testLibs"}
+	stdout, err := CaptureStdout(t, generateCommand.Execute)
+	assert.NoError(t, err)
+	assert.Contains(t, stdout, `  Source sets:
+    com.squareup.okio:okio: jvmMain
+    io.ktor:ktor-client-core: commonMain
+    io.ktor:ktor-client-mock: jvmTest
+    org.jetbrains.kotlinx:kotlinx-coroutines-test: commonTest`)
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+com-squareup-okio-okio = { group = "com.squareup.okio", name = "okio", version = "3.9.0" }
+io-ktor-ktor-client-core = { group = "io.ktor", name = "ktor-client-core", version = "2.3.11" }
+`, string(f))
+	f, _ = os.ReadFile(filepath.Join(tempdir, "gradle", "testLibs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[libraries]
+io-ktor-ktor-client-mock = { group = "io.ktor", name = "ktor-client-mock", version = "2.3.11" }
+org-jetbrains-kotlinx-kotlinx-coroutines-test = { group = "org.jetbrains.kotlinx", name = "kotlinx-coroutines-test", version = "1.8.1" }
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "shared", "build.gradle.kts"))
+	assert.Equal(t, `plugins {
+    kotlin("multiplatform")
+}
+kotlin {
+    jvm()
+    js { browser() }
+    sourceSets {
+        commonMain.dependencies {
+            implementation( libs.io.ktor.ktor.client.core )
+            implementation(project(":core"))
+        }
+        commonTest.dependencies {
+            implementation(kotlin("test"))
+            implementation(testLibs.org.jetbrains.kotlinx.kotlinx.coroutines.test)
+        }
+        val jvmTest by getting {
+            dependencies {
+                implementation(testLibs.io.ktor.ktor.client.mock)
+            }
+        }
+        jsMain {
+            dependencies {
+                implementation(npm("left-pad", "1.3.0"))
+                implementation(devNpm("sass:loader", "13.0.0"))
+            }
+        }
+    }
+}
+dependencies {
+    jvmMainImplementation(libs.com.squareup.okio.okio)
+}
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "legacy", "build.gradle"))
+	assert.Contains(t, string(f), "implementation libs.io.ktor.ktor.client.core")
+}
+
+func TestMultiplatformByConventionPlugin(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `include(":shared", ":jvm")`)
+	writeFile(t, tempdir, "shared/build.gradle.kts", `plugins {
+    id("convention.kmp")
+}
+kotlin {
+    sourceSets {
+        commonMain.dependencies {
+            implementation("io.ktor:ktor-client-core:2.3.11")
+        }
+    }
+}
+`)
+	writeFile(t, tempdir, "jvm/build.gradle.kts", `plugins {
+    // kotlin("multiplatform")
+    kotlin("jvm")
+}
+dependencies {
+    integrationTestImplementation("com.squareup.okio:okio:3.9.0")
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	stdout, err := CaptureStdout(t, generateCommand.Execute)
+	assert.NoError(t, err)
+	assert.Contains(t, stdout, `  Source sets:
+    io.ktor:ktor-client-core: commonMain`)
+	assert.NotContains(t, stdout, "integrationTest")
+}
+
+func TestResolveDependencyObjectsInVersions(t *testing.T) {
+	resetFlagsOnCleanup(t, generateCommand)
+	tempdir := t.TempDir()
+	writeFile(t, tempdir, "gradle/wrapper/dummy.txt", "")
+	writeFile(t, tempdir, "settings.gradle.kts", `include(":app")`)
+	writeFile(t, tempdir, "buildSrc/build.gradle.kts", `plugins {
+    `+"`kotlin-dsl`"+`
+}
+`)
+	writeFile(t, tempdir, "buildSrc/src/main/kotlin/Dependencies.kt", `object Versions {
+    const val kotlin = "1.9.24"
+    const val OKHTTP_BOM = "4.12.0"
+    const val channel = "beta"
+}
+`)
+	writeFile(t, tempdir, "app/build.gradle.kts", `dependencies {
+    implementation("org.jetbrains.kotlin:kotlin-stdlib:${Versions.kotlin}")
+    implementation(platform("com.squareup.okhttp3:okhttp-bom:${Versions.OKHTTP_BOM}"))
+    implementation("com.example:nightly:${Versions.channel}")
+    implementation("com.example:preview:${Versions.kotlin}-${Versions.channel}")
+}
+`)
+
+	os.Args = []string{"cli", "generate", tempdir, "--auto-latest=false"}
+	assert.NoError(t, generateCommand.Execute())
+
+	f, _ := os.ReadFile(filepath.Join(tempdir, "gradle", "libs.versions.toml"))
+	compareIgnoreLineBreaks(t, `[versions]
+kotlin = "1.9.24"
+okhttp-bom = "4.12.0"
+
+[libraries]
+com-squareup-okhttp3-okhttp-bom = { group = "com.squareup.okhttp3", name = "okhttp-bom", version.ref = "okhttp-bom" }
+org-jetbrains-kotlin-kotlin-stdlib = { group = "org.jetbrains.kotlin", name = "kotlin-stdlib", version.ref = "kotlin" }
+
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "app", "build.gradle.kts"))
+	compareIgnoreLineBreaks(t, `dependencies {
+    implementation(libs.org.jetbrains.kotlin.kotlin.stdlib)
+    implementation(platform(libs.com.squareup.okhttp3.okhttp.bom))
+    implementation("com.example:nightly:${Versions.channel}")
+    implementation("com.example:preview:${Versions.kotlin}-${Versions.channel}")
+}
+`, string(f))
+
+	f, _ = os.ReadFile(filepath.Join(tempdir, "buildSrc", "src", "main", "kotlin", "Dependencies.kt"))
+	compareIgnoreLineBreaks(t, `object Versions {
+    @Deprecated("Use libs.versions.kotlin from the version catalog")
+    const val kotlin = "1.9.24"
+    @Deprecated("Use libs.versions.okhttp.bom from the version catalog")
+    const val OKHTTP_BOM = "4.12.0"
+    const val channel = "beta"
+}
+`, string(f))
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitWorkTree is the git work tree the Gradle project lives in.
+type GitWorkTree struct {
+	Root string
+}
+
+// findGitWorkTree looks for a .git directory, or a .git file of a linked work tree or submodule,
+// in dir and its parents, and asks git itself when there is none (e.g. GIT_DIR is set).
+// It returns nil if dir is not inside a work tree.
+func findGitWorkTree(dir string) (*GitWorkTree, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for current := dir; ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return &GitWorkTree{Root: current}, nil
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		current = parent
+	}
+	if !isGitAvailable() {
+		return nil, nil
+	}
+	output, err := GitWorkTree{Root: dir}.run("", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, nil
+	}
+	return &GitWorkTree{Root: filepath.Clean(strings.TrimSpace(output))}, nil
+}
+
+func isGitAvailable() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+}
+
+// inspectableWorkTree returns workTree if the git command can inspect it, and nil if git is not installed.
+// Without git uncommitted changes cannot be detected, so that fails unless allowDirty.
+func inspectableWorkTree(workTree *GitWorkTree, allowDirty bool) (*GitWorkTree, error) {
+	if workTree == nil || isGitAvailable() {
+		return workTree, nil
+	}
+	if !allowDirty {
+		return nil, fmt.Errorf("%s is a git work tree but git is not installed, so uncommitted changes cannot be detected; pass --allow-dirty to rewrite files anyway", workTree.Root)
+	}
+	fmt.Printf("NOTICE: %s is a git work tree but git is not installed, skipping the repository checks.%s", workTree.Root, LineBreak)
+	return nil, nil
+}
+
+func (w GitWorkTree) run(stdin string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", w.Root}, args...)...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return stdout.String(), fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// relative turns paths into work tree relative ones, which is what git prints.
+func (w GitWorkTree) relative(paths []string) ([]string, error) {
+	relatives := make([]string, len(paths))
+	for i, path := range paths {
+		absolute, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		relative, err := filepath.Rel(w.Root, absolute)
+		if err != nil {
+			return nil, err
+		}
+		relatives[i] = filepath.ToSlash(relative)
+	}
+	return relatives, nil
+}
+
+// dirtyFiles returns those of paths that have staged, unstaged or untracked changes.
+func (w GitWorkTree) dirtyFiles(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	relatives, err := w.relative(paths)
+	if err != nil {
+		return nil, err
+	}
+	output, err := w.run("", append([]string{"status", "--porcelain", "-z", "--"}, relatives...)...)
+	if err != nil {
+		return nil, err
+	}
+	dirty := make(map[string]bool)
+	entries := strings.Split(output, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		dirty[entry[3:]] = true
+		if entry[0] == 'R' || entry[0] == 'C' {
+			// the source path of a rename or copy follows as its own entry
+			i++
+		}
+	}
+	files := make([]string, 0)
+	for i, relative := range relatives {
+		if dirty[relative] {
+			files = append(files, paths[i])
+		}
+	}
+	return files, nil
+}
+
+// ensureClean fails if any of paths has uncommitted changes.
+func (w GitWorkTree) ensureClean(paths []string) error {
+	dirtyFiles, err := w.dirtyFiles(paths)
+	if err != nil {
+		return fmt.Errorf("failed to check the git status: %w", err)
+	}
+	if len(dirtyFiles) > 0 {
+		return fmt.Errorf("files to be rewritten have uncommitted changes, commit or stash them, or pass --allow-dirty:%s  %s", LineBreak, strings.Join(dirtyFiles, LineBreak+"  "))
+	}
+	return nil
+}
+
+// ignoredFiles returns those of paths that are matched by .gitignore.
+func (w GitWorkTree) ignoredFiles(paths []string) (map[string]bool, error) {
+	ignored := make(map[string]bool)
+	if len(paths) == 0 {
+		return ignored, nil
+	}
+	relatives, err := w.relative(paths)
+	if err != nil {
+		return nil, err
+	}
+	output, err := w.run(strings.Join(relatives, "\x00")+"\x00", "check-ignore", "--stdin", "-z")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		// exit code 1 means none of the paths is ignored
+		return ignored, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	matched := make(map[string]bool)
+	for _, relative := range strings.Split(output, "\x00") {
+		matched[relative] = true
+	}
+	for i, relative := range relatives {
+		if matched[relative] {
+			ignored[paths[i]] = true
+		}
+	}
+	return ignored, nil
+}
+
+// changedFiles returns the absolute paths of the files that differ between ref and the work tree,
+// including untracked ones.
+func (w GitWorkTree) changedFiles(ref string) (map[string]bool, error) {
+	changed := make(map[string]bool)
+	for _, args := range [][]string{
+		{"diff", "--name-only", "-z", ref, "--"},
+		{"ls-files", "--others", "--exclude-standard", "-z"},
+	} {
+		output, err := w.run("", args...)
+		if err != nil {
+			return nil, err
+		}
+		for _, relative := range strings.Split(output, "\x00") {
+			if relative != "" {
+				changed[filepath.Join(w.Root, filepath.FromSlash(relative))] = true
+			}
+		}
+	}
+	return changed, nil
+}
+
+// commit records paths as a single commit, leaving anything else in the index untouched.
+func (w GitWorkTree) commit(paths []string, message string) error {
+	relatives, err := w.relative(paths)
+	if err != nil {
+		return err
+	}
+	if _, err := w.run("", append([]string{"add", "--"}, relatives...)...); err != nil {
+		return err
+	}
+	_, err = w.run("", append([]string{"commit", "--quiet", "-m", message, "--"}, relatives...)...)
+	return err
+}
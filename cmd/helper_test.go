@@ -2,8 +2,11 @@ package cmd
 
 import (
 	"bytes"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
 	"testing"
@@ -48,3 +51,36 @@ func compareIgnoreLineBreaks(t *testing.T, expected, actual string) {
 	re := regexp.MustCompile(`\s+`)
 	assert.Equal(t, re.ReplaceAllString(expected, "\n"), re.ReplaceAllString(actual, "\n"))
 }
+
+// resetFlagsOnCleanup restores the flags of command to their defaults after the test,
+// since cobra keeps the parsed values across executions of the same command.
+func resetFlagsOnCleanup(t *testing.T, command *cobra.Command) {
+	t.Cleanup(func() {
+		command.Flags().VisitAll(func(flag *pflag.Flag) {
+			if slice, ok := flag.Value.(pflag.SliceValue); ok {
+				assert.NoError(t, slice.Replace(nil))
+			} else {
+				assert.NoError(t, flag.Value.Set(flag.DefValue))
+			}
+			flag.Changed = false
+		})
+	})
+}
+
+// gitCommitAll turns dir into a git repository whose files are all committed.
+func gitCommitAll(t *testing.T, dir string) {
+	t.Helper()
+	if !isGitAvailable() {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	for _, args := range [][]string{{"init", "--quiet"}, {"add", "-A"}, {"commit", "--quiet", "-m", "initial"}} {
+		output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, output)
+		}
+	}
+}
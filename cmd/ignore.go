@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Segment is a run of consecutive lines of a build script, either migrated or excluded via markers:
+//
+//	implementation("foo:bar:1.0") // gvc:ignore
+//	// gvc:ignore-next-line
+//	// gvc:off
+//	// gvc:on
+type Segment struct {
+	Text    string
+	Ignored bool
+	// Line is the 1-based line number where the segment starts.
+	Line int
+}
+
+var ignoreMarker = regexp.MustCompile(`//\s*gvc:(ignore-next-line|ignore|off|on)\b`)
+
+func splitIgnoredSegments(content string) []Segment {
+	segments := make([]Segment, 0)
+	off := false
+	ignoreNext := false
+	for i, line := range strings.SplitAfter(content, "\n") {
+		if line == "" {
+			continue
+		}
+		ignored := off || ignoreNext
+		ignoreNext = false
+		if match := ignoreMarker.FindStringSubmatch(line); match != nil {
+			switch match[1] {
+			case "ignore":
+				ignored = true
+			case "ignore-next-line":
+				ignoreNext = true
+			case "off":
+				off = true
+				ignored = true
+			case "on":
+				off = false
+				ignored = true
+			}
+		}
+
+		if last := len(segments) - 1; last >= 0 && segments[last].Ignored == ignored {
+			segments[last].Text += line
+		} else {
+			segments = append(segments, Segment{Text: line, Ignored: ignored, Line: i + 1})
+		}
+	}
+	return segments
+}
+
+// activeText blanks out the ignored lines, keeping line numbers intact.
+func activeText(segments []Segment) string {
+	var builder strings.Builder
+	for _, segment := range segments {
+		if segment.Ignored {
+			builder.WriteString(strings.Repeat("\n", strings.Count(segment.Text, "\n")))
+		} else {
+			builder.WriteString(segment.Text)
+		}
+	}
+	return builder.String()
+}
+
+// rewriteActiveSegments applies rewrite to every segment that is not ignored.
+func rewriteActiveSegments(content string, rewrite func(string) string) string {
+	var builder strings.Builder
+	for _, segment := range splitIgnoredSegments(content) {
+		if segment.Ignored {
+			builder.WriteString(segment.Text)
+		} else {
+			builder.WriteString(rewrite(segment.Text))
+		}
+	}
+	return builder.String()
+}
+
+// findIgnoredDeclarations lists the declarations inside ignored segments.
+func findIgnoredDeclarations(extractor StaticExtractors, path string, segments []Segment) []SkippedDeclaration {
+	skipped := make([]SkippedDeclaration, 0)
+	for _, segment := range segments {
+		if !segment.Ignored {
+			continue
+		}
+		for _, re := range []*regexp.Regexp{&extractor.libraryString, &extractor.libraryMap, &extractor.plugin} {
+			for _, loc := range re.FindAllStringIndex(segment.Text, -1) {
+				matched := segment.Text[loc[0]:loc[1]]
+				start := loc[0] + len(matched) - len(strings.TrimLeft(matched, " \t\r\n"))
+				skipped = append(skipped, SkippedDeclaration{
+					Path:        path,
+					Line:        segment.Line + strings.Count(segment.Text[:start], "\n"),
+					Declaration: strings.TrimSpace(matched),
+				})
+			}
+		}
+	}
+	slices.SortStableFunc(skipped, func(a, b SkippedDeclaration) int {
+		return a.Line - b.Line
+	})
+	return skipped
+}
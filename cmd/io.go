@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 	"unicode"
@@ -52,7 +53,7 @@ func findBuildGradle(root string, depth int, currentDepth int) ([]string, error)
 				continue
 			}
 			buildGradleFiles = append(buildGradleFiles, path)
-		} else if entry.IsDir() {
+		} else if entry.IsDir() && !isSkippedDirectory(path) {
 			subFiles, err := findBuildGradle(path, depth, currentDepth+1)
 			if err != nil {
 				return nil, err // Propagate the error if needed
@@ -80,7 +81,6 @@ func getConfigurations() []string {
 		"testCompileOnly",
 		"testRuntimeOnly",
 		"force",
-		//"resolutionStrategy.force",
 	}
 }
 
@@ -97,29 +97,26 @@ func getStaticExtractors() StaticExtractors {
 }
 
 func compileLibraryStringNotationExtractor() regexp.Regexp {
-	configPattern := strings.Join(getConfigurations(), "|")
-	libraryPattern := "(?P<group>[^:\"']+):(?P<name>[^:\"']+)(?::(?P<version>[^:\"']+)(?::(?P<classifier>[a-zA-Z0-9_-]+))?)?"
-	return *regexp.MustCompile(fmt.Sprintf(`(?P<config>%s)\s*\(?["']%s["']\)?`, configPattern, libraryPattern))
+	configPattern := sourceSetConfigurationPattern + "|" + strings.Join(getConfigurations(), "|")
+	libraryPattern := "(?P<group>[^:\"'@]+):(?P<name>[^:\"'@]+)(?::(?P<version>\\$\\{[^{}\\r\\n]*}|[^:\"'@]+)(?::(?P<classifier>[a-zA-Z0-9_-]+))?)?(?:@(?P<ext>[a-zA-Z0-9_-]+))?"
+	return *regexp.MustCompile(fmt.Sprintf(`(?P<config>%s)(?P<open>\s*\(\s*|\s*)(?P<quote>["'])%s["'](?P<close>\s*\))?`, configPattern, libraryPattern))
 }
 
 func compileLibraryMapNotationExtractor() regexp.Regexp {
-	configPattern := strings.Join(getConfigurations(), "|")
-	libraryPattern := "group\\s*[=:]\\s*[\"'](?P<group>[^:\"']+)[\"']\\s*,\\s*name\\s*[=:]\\s*[\"'](?P<name>[^:\"']+)[\"'](?:\\s*,\\s*version\\s*[=:]\\s*(?P<version>(?:\"\\$?\\{?[a-zA-Z0-9._-]+\\}?\"|'\\$?\\{?[a-zA-Z0-9._-]+\\}?'|[a-zA-Z0-9_]+)))?"
-	return *regexp.MustCompile(fmt.Sprintf(`(?P<config>%s)\s*\(?%s\)?`, configPattern, libraryPattern))
+	configPattern := sourceSetConfigurationPattern + "|" + strings.Join(getConfigurations(), "|")
+	libraryPattern := "group\\s*[=:]\\s*[\"'](?P<group>[^:\"']+)[\"']\\s*,\\s*name\\s*[=:]\\s*[\"'](?P<name>[^:\"']+)[\"'](?:\\s*,\\s*version\\s*[=:]\\s*(?P<version>(?:\"\\$\\{[^{}\\r\\n]*}\"|\"[^\"'\\r\\n]+\"|'[^\"'\\r\\n]+'|[a-zA-Z0-9_]+)))?"
+	// classifier, ext and configuration select an artifact or a configuration of the module
+	argumentsPattern := `(?P<arguments>(?:\s*,\s*(?:classifier|ext|configuration)\s*[=:]\s*["'][^"'\r\n]*["'])*)`
+	return *regexp.MustCompile(fmt.Sprintf(`(?P<config>%s)(?P<open>\s*\(\s*|\s*)%s%s(?P<close>\s*\))?`, configPattern, libraryPattern, argumentsPattern))
 }
 
 func compilePluginExtractor() regexp.Regexp {
-	return *regexp.MustCompile(`(\W)id\W+(?P<id>[\w.-]+)\W+version[ ("']+(?P<version>[\w.${}-]+)["')]+`)
+	return *regexp.MustCompile(`(?m)(?P<leading>^|\W)id(?P<open>\W+)(?P<id>[\w.-]+)\W+version[ ("']+(?P<version>[\w.${}-]+)["')]+`)
 }
 
-func compileVersionVariableExtractor(keys []string) regexp.Regexp {
-	combinedKeys := strings.Join(keys, "|")
-	return *regexp.MustCompile(fmt.Sprintf(`\W(%s)\W?=\W*["']([^"']+)["']`, combinedKeys))
-}
-
-func compileVersionVariableInPropertyFileExtractor(keys []string) regexp.Regexp {
-	combinedKeys := strings.Join(keys, "|")
-	return *regexp.MustCompile(fmt.Sprintf(`\s*(%s)\W?=\W*([^"'\r\n]+)`, combinedKeys))
+// submatch returns the named capturing group of a match produced by re.
+func submatch(re *regexp.Regexp, match []string, name string) string {
+	return match[re.SubexpIndex(name)]
 }
 
 func escapeVersionVariableName(name string) string {
@@ -132,72 +129,114 @@ func escapeVersionVariableName(name string) string {
 	return strings.ReplaceAll(name, ".", "_")
 }
 
+// versionVariableOf returns the name of the variable a declared version refers to, e.g. fooVersion for "$fooVersion".
+// A quoted version is a string literal, an unquoted one an expression of map notation, e.g. version = fooVersion
+func versionVariableOf(version string, quoted bool) (string, bool) {
+	if version == "" || version == "FIXME" {
+		return "", false
+	}
+	if !quoted {
+		return escapeVersionVariableName(version), true
+	}
+	if !strings.HasPrefix(version, "$") {
+		return "", false
+	}
+	name := extractVariableName(version)
+	if match := propertyReadExtractor.FindStringSubmatch(name); match != nil {
+		// read as a property of the project, which is looked up like ${project.x}
+		owner := submatch(propertyReadExtractor, match, "owner")
+		if owner == "" {
+			owner = "project"
+		}
+		// reads is captured by one of the alternatives
+		for i, group := range propertyReadExtractor.SubexpNames() {
+			if group == "reads" && match[i] != "" {
+				name = owner + "." + match[i]
+			}
+		}
+	}
+	return escapeVersionVariableName(name), true
+}
+
+// propertyReadExtractor matches the interpolated expressions reading a property,
+// e.g. property("x"), project.findProperty("x"), rootProject.extra["x"] or project.'x' in Groovy
+var propertyReadExtractor = regexp.MustCompile(`^(?:(?P<owner>project|rootProject)\.)?(?:(?:property|findProperty)\(\s*["'](?P<reads>\w+)["']\s*\)|ext(?:ra)?\[\s*["'](?P<reads>\w+)["']\s*]|'(?P<reads>\w+)')$`)
+
 func extractTemp(extractor StaticExtractors, text string) (Versions, []Plugin, []StrictLibrary) {
 	versions := make(Versions, 0)
 
 	allMatchedLibs := extractor.libraryString.FindAllStringSubmatch(text, -1)
 	allMatchedMaps := extractor.libraryMap.FindAllStringSubmatch(text, -1)
+	libEnds := extractor.libraryString.FindAllStringIndex(text, -1)
+	mapEnds := extractor.libraryMap.FindAllStringIndex(text, -1)
 
 	libs := make([]StrictLibrary, len(allMatchedLibs)+len(allMatchedMaps))
+	sourceSets, multiplatform := findSourceSetBlocks(text)
 	for i, match := range allMatchedLibs {
-		var version string
-		if match[4] == "" {
+		version := submatch(&extractor.libraryString, match, "version")
+		if version == "" {
 			version = "FIXME"
-		} else {
-			version = escapeVersionVariableName(match[4])
 		}
 		libs[i] = StrictLibrary{
-			Group:   match[2],
-			Name:    match[3],
-			Version: version,
+			Group:         submatch(&extractor.libraryString, match, "group"),
+			Name:          submatch(&extractor.libraryString, match, "name"),
+			Version:       version,
+			Declared:      submatch(&extractor.libraryString, match, "version"),
+			Configuration: submatch(&extractor.libraryString, match, "config"),
+		}
+		if multiplatform {
+			libs[i].SourceSet, libs[i].Configuration = sourceSetOf(sourceSets, libEnds[i][0], libs[i].Configuration)
 		}
 
-		if strings.HasPrefix(version, "$") {
-			key := extractVariableName(version)
+		if key, ok := versionVariableOf(version, true); ok {
 			versions[key] = "FIXME"
 			libs[i].Version = "$" + key
+		} else if rich := declaredRichVersion(text, libEnds[i][1], version); rich != nil {
+			libs[i].Rich = rich
+			libs[i].Version = representativeVersion(rich)
 		}
 	}
 
 	lastLength := len(allMatchedLibs)
 	for i, match := range allMatchedMaps {
 		var version string
-		version, hasQuote := unquote(match[4])
+		version, hasQuote := unquote(submatch(&extractor.libraryMap, match, "version"))
 		if version == "" {
 			hasQuote = true
 			version = "FIXME"
 		}
 		libs[i+lastLength] = StrictLibrary{
-			Group:   match[2],
-			Name:    match[3],
-			Version: version,
+			Group:         submatch(&extractor.libraryMap, match, "group"),
+			Name:          submatch(&extractor.libraryMap, match, "name"),
+			Version:       version,
+			Declared:      submatch(&extractor.libraryMap, match, "version"),
+			Configuration: submatch(&extractor.libraryMap, match, "config"),
+		}
+		if multiplatform {
+			libs[i+lastLength].SourceSet, libs[i+lastLength].Configuration = sourceSetOf(sourceSets, mapEnds[i][0], libs[i+lastLength].Configuration)
 		}
 
-		if hasQuote {
-			// version = "$fooVer"
-			if strings.HasPrefix(version, "$") {
-				key := extractVariableName(escapeVersionVariableName(version))
-				versions[key] = "FIXME"
-				libs[i+lastLength].Version = "$" + key
-			}
-		} else {
-			// version = fooVer
-			key := escapeVersionVariableName(version)
+		// version = "$fooVer" or version = fooVer
+		if key, ok := versionVariableOf(version, hasQuote); ok {
 			versions[key] = "FIXME"
 			libs[i+lastLength].Version = "$" + key
+		} else if rich := declaredRichVersion(text, mapEnds[i][1], version); rich != nil {
+			libs[i+lastLength].Rich = rich
+			libs[i+lastLength].Version = representativeVersion(rich)
 		}
 	}
 
 	allMatchedPlugins := extractor.plugin.FindAllStringSubmatch(text, -1)
 	plugins := make([]Plugin, len(allMatchedPlugins))
 	for i, match := range allMatchedPlugins {
+		version := submatch(&extractor.plugin, match, "version")
 		plugins[i] = Plugin{
-			Id:      match[2],
-			Version: match[3],
+			Id:      submatch(&extractor.plugin, match, "id"),
+			Version: version,
 		}
 
-		if strings.HasPrefix(match[3], "$") {
-			key := extractVariableName(match[3])
+		if strings.HasPrefix(version, "$") {
+			key := extractVariableName(version)
 			versions[key] = "FIXME"
 			plugins[i].Version = LooseLibrary{
 				"ref": key,
@@ -208,21 +247,13 @@ func extractTemp(extractor StaticExtractors, text string) (Versions, []Plugin, [
 	return versions, plugins, libs
 }
 
-func extractVersionInPropertyFile(extractor regexp.Regexp, text string) Versions {
-	versions := make(Versions, 0)
-	allMatchedLibs := extractor.FindAllStringSubmatch(text, -1)
-	for _, match := range allMatchedLibs {
-		key := match[1]
-		version := match[2]
-		versions[key] = version
-	}
-	return versions
-}
-
 var variableNameExtractor = regexp.MustCompile(`^\$(?:\{(.+)}|([^{}]+))$`)
 
 func extractVariableName(name string) string {
 	submatch := variableNameExtractor.FindStringSubmatch(name)
+	if submatch == nil {
+		return name
+	}
 	if len(submatch[1]) > 0 {
 		return submatch[1]
 	}
@@ -232,25 +263,17 @@ func extractVariableName(name string) string {
 	return name
 }
 
-var quoteExtractor = regexp.MustCompile(`^["']([^"']*)["']$`)
+// a string literal, whose interpolations may hold quotes, e.g. "${property("x")}"
+var quoteExtractor = regexp.MustCompile(`^(?:"((?:\$\{[^{}\r\n]*}|[^"'])*)"|'([^"']*)')$`)
 
 func unquote(name string) (string, bool) {
 	submatch := quoteExtractor.FindStringSubmatch(name)
-	if len(submatch) > 1 {
-		return submatch[1], true
+	if submatch != nil {
+		return submatch[1] + submatch[2], true
 	}
 	return name, false
 }
 
-func extractVersionVariables(versions Versions, extractor regexp.Regexp, text string) {
-	allMatches := extractor.FindAllStringSubmatch(text, -1)
-	for _, match := range allMatches {
-		key := match[1]
-		version := match[2]
-		versions[key] = version
-	}
-}
-
 var nonIdChars = regexp.MustCompile("[^a-zA-Z0-9_-]+")
 var numericFollowingSeparator = regexp.MustCompile("-[0-9]+")
 
@@ -271,25 +294,145 @@ func safeKey(src string) string {
 	})
 }
 
-func updateCatalog(catalog VersionCatalog, libraries []StrictLibrary) {
+func updateCatalogPlugins(catalog VersionCatalog, plugins []Plugin, aliases Aliases, merger catalogMerger) {
+	existingAliases := indexPluginAliases(catalog.Plugins)
+	for _, plugin := range plugins {
+		key, exists := existingAliases[plugin.Id]
+		if !exists {
+			key = catalogSafeKeyPlugin(plugin)
+			catalog.Plugins[key] = plugin
+			continue
+		}
+
+		aliases.plugins[plugin.Id] = key
+		existing := catalog.Plugins[key]
+		if ref, ok := versionRefOf(existing.Version); ok {
+			resolvedVersion := plugin.Version
+			if scannedRef, ok := versionRefOf(plugin.Version); ok {
+				resolvedVersion = catalog.Versions[scannedRef]
+			}
+			mergeVersionValue(catalog, merger, ref, resolvedVersion)
+			continue
+		}
+		existing.Version = merger.merge("plugins."+key, existing.Version, plugin.Version)
+		catalog.Plugins[key] = existing
+	}
+}
+
+// resolveVersion returns the value a declared version stands for, the [versions] value for a reference, e.g. $guava.
+func resolveVersion(catalog VersionCatalog, version string) any {
+	if ref, ok := strings.CutPrefix(version, "$"); ok {
+		return catalog.Versions[ref]
+	}
+	return version
+}
+
+// mergeVersionValue stores a scanned value under a [versions] key according to the merge policy.
+// The scanned value is a plain version or a rich one.
+func mergeVersionValue(catalog VersionCatalog, merger catalogMerger, key string, scanned any) {
+	if scanned == nil || scanned == "" {
+		return
+	}
+	var existing any
+	if value, ok := catalog.Versions[key]; ok {
+		existing = value
+	}
+	catalog.Versions[key] = merger.merge("versions."+key, existing, scanned)
+}
+
+// reuseLibraryEntry updates an entry already in the catalog, keeping its notation.
+// If the entry refers to a [versions] key, the scanned version is stored under that key.
+func reuseLibraryEntry(catalog VersionCatalog, merger catalogMerger, alias string, resolvedVersion any, version any) LooseLibrary {
+	entry := catalog.Libraries[alias]
+	updated := maps.Clone(entry)
+	if ref, ok := versionRefOf(entry["version"]); ok {
+		mergeVersionValue(catalog, merger, ref, resolvedVersion)
+		return updated
+	}
+	updated["version"] = merger.merge("libraries."+alias, entry["version"], version)
+	return updated
+}
+
+func toCatalogVersion(version string) any {
+	if strings.HasPrefix(version, "$") {
+		return LooseLibrary{
+			"ref": version[1:],
+		}
+	}
+	return version
+}
+
+func updateCatalog(catalog VersionCatalog, libraries []StrictLibrary, options ExtractOptions, merger catalogMerger) (Aliases, error) {
+	report := merger.report
+	aliases := newAliases(options.CatalogName)
+	existingAliases := indexLibraryAliases(catalog.Libraries)
+
+	coordinates := make([]string, 0)
+	usages := make(map[string][]StrictLibrary)
 	for _, lib := range libraries {
-		var version any
-		if strings.HasPrefix(lib.Version, "$") {
-			trimmedVersion := lib.Version[1:]
-			version = LooseLibrary{
-				"ref": trimmedVersion,
+		coordinate := lib.coordinate()
+		if _, ok := usages[coordinate]; !ok {
+			coordinates = append(coordinates, coordinate)
+		}
+		usages[coordinate] = append(usages[coordinate], lib)
+	}
+
+	resolvedOf := func(lib StrictLibrary) any {
+		if lib.Version == "FIXME" {
+			return ""
+		}
+		return resolveVersion(catalog, lib.Version)
+	}
+	versionOf := func(lib StrictLibrary) string {
+		version := plainVersion(resolvedOf(lib))
+		if version == "FIXME" {
+			return ""
+		}
+		return version
+	}
+
+	failed := make([]string, 0)
+	for _, coordinate := range coordinates {
+		chosen, conflict := resolveConflict(coordinate, usages[coordinate], versionOf, options.ConflictStrategy)
+		if conflict != nil {
+			report.Conflicts = append(report.Conflicts, *conflict)
+			if chosen == nil {
+				failed = append(failed, conflict.String())
+				continue
 			}
-		} else {
-			version = lib.Version
 		}
 
-		key := catalogSafeKey(lib)
-		catalog.Libraries[key] = LooseLibrary{
-			"group":   lib.Group,
-			"name":    lib.Name,
-			"version": version,
+		key, exists := existingAliases[coordinate]
+		if !exists {
+			key = catalogSafeKey(usages[coordinate][0])
+		}
+		aliases.libraries[coordinate] = key
+		for suffix, lib := range chosen {
+			if exists && suffix == "" {
+				catalog.Libraries[key] = reuseLibraryEntry(catalog, merger, key, resolvedOf(lib), lib.catalogVersion())
+				continue
+			}
+			catalog.Libraries[key+suffix] = LooseLibrary{
+				"group":   lib.Group,
+				"name":    lib.Name,
+				"version": lib.catalogVersion(),
+			}
+		}
+		if len(chosen) > 1 {
+			for _, usage := range usages[coordinate] {
+				for suffix, lib := range chosen {
+					if suffix != "" && versionOf(usage) == versionOf(lib) {
+						aliases.setForFile(usage.Path, usage, key+suffix)
+					}
+				}
+			}
 		}
 	}
+
+	if len(failed) > 0 {
+		return aliases, fmt.Errorf("conflicting versions found:%s  %s", LineBreak, strings.Join(failed, LineBreak+"  "))
+	}
+	return aliases, nil
 }
 
 func initVersionCatalog() VersionCatalog {
@@ -301,78 +444,140 @@ func initVersionCatalog() VersionCatalog {
 	return catalog
 }
 
-type EmbedResult struct {
-	UpdatedBuildSrc bool
-	WrittenInKotlin bool
-}
-
-func embedReferenceToLibs(buildFilePaths []string) (EmbedResult, error) {
-	if len(buildFilePaths) == 0 {
-		return EmbedResult{}, nil
-	}
-
+// embedReferenceToLibs rewrites the declarations in build files to catalog accessors
+// and returns the files that changed.
+// Precompiled script plugins are rewritten in the given style.
+func embedReferenceToLibs(buildFilePaths []string, aliases Aliases, style PrecompiledStyle, changes *ChangeSet) ([]string, error) {
 	extractor := getStaticExtractors()
-	UpdatedBuildSrc := false
+	updated := make([]string, 0)
 
 	for _, buildFilePath := range buildFilePaths {
-		bytes, err := os.ReadFile(buildFilePath)
+		originalContent, err := changes.read(buildFilePath)
 		if err != nil {
-			return EmbedResult{}, err
-		}
-		originalContent := string(bytes)
-		updatedContent := extractor.libraryString.ReplaceAllStringFunc(originalContent, func(s string) string {
-			match := extractor.libraryString.FindStringSubmatch(s)
-			config := match[1]
-			key := strings.ReplaceAll(catalogSafeKey(StrictLibrary{
-				Group:   match[2],
-				Name:    match[3],
-				Version: match[4],
-			}), "-", ".")
-			classifier := match[5]
-			if classifier == "" {
-				return fmt.Sprintf("%s(libs.%s)", config, key)
-			} else {
-				return fmt.Sprintf(`%s(variantOf(libs.%s) { classifier("%s") })`, config, key, classifier)
-			}
-		})
-
-		updatedContent = extractor.libraryMap.ReplaceAllStringFunc(updatedContent, func(s string) string {
-			match := extractor.libraryMap.FindStringSubmatch(s)
-			config := match[1]
-			key := strings.ReplaceAll(catalogSafeKey(StrictLibrary{
-				Group:   match[2],
-				Name:    match[3],
-				Version: match[4],
-			}), "-", ".")
-			return fmt.Sprintf("%s(libs.%s)", config, key)
-		})
-
-		updatedContent = extractor.plugin.ReplaceAllStringFunc(updatedContent, func(s string) string {
-			match := extractor.plugin.FindStringSubmatch(s)
-			key := strings.ReplaceAll(catalogSafeKeyPlugin(Plugin{
-				Id:      match[2],
-				Version: match[3],
-			}), "-", ".")
-			leading := match[1]
-			return fmt.Sprintf("%salias(libs.plugins.%s)", leading, key)
+			return nil, err
+		}
+		updatedContent := rewriteActiveSegments(originalContent, func(text string) string {
+			return rewriteBuildScript(extractor, dialectOf(buildFilePath), text, buildFilePath, aliases, style)
 		})
+		if isPrecompiledScriptPlugin(buildFilePath) && !usesCatalogAPI(buildFilePath, style) {
+			updatedContent = declareLibrariesFor(updatedContent, aliases.catalogNames())
+		}
 
 		if originalContent == updatedContent {
 			// No changes made, skip writing
 			continue
 		}
 
-		if strings.Contains(buildFilePath, "buildSrc") {
-			UpdatedBuildSrc = true
+		updated = append(updated, buildFilePath)
+		changes.write(buildFilePath, updatedContent)
+	}
+	return updated, nil
+}
+
+func rewriteBuildScript(extractor StaticExtractors, dialect Dialect, content string, path string, aliases Aliases, style PrecompiledStyle) string {
+	accessorOf := func(lib StrictLibrary) string {
+		alias := aliases.library(path, lib)
+		if usesCatalogAPI(path, style) {
+			return catalogAPILibrary(dialect, aliases.libraryCatalog(alias), alias)
+		}
+		return libraryAccessor(aliases.libraryCatalog(alias), alias)
+	}
+	// closureOf returns the closure following a declaration, if any
+	closureOf := func(following string) string {
+		if brace, ok := closureFollowing(following); ok {
+			return blockBody(following, brace)
+		}
+		return ""
+	}
+	content = rewriteResolutionRules(dialect, content, path, aliases, style)
+	content = rewriteDependencyManagement(dialect, content, path, aliases, style)
+	libraryString := &extractor.libraryString
+	updatedContent := replaceDeclarations(libraryString, removeVersionBlocks(extractor, content), func(s string, following string) (string, int) {
+		match := libraryString.FindStringSubmatch(s)
+		if aliases.isUnresolved(path, submatch(libraryString, match, "version"), true) {
+			return s, 0
 		}
+		accessor := accessorOf(StrictLibrary{
+			Group:   submatch(libraryString, match, "group"),
+			Name:    submatch(libraryString, match, "name"),
+			Version: submatch(libraryString, match, "version"),
+		})
+		quote := submatch(libraryString, match, "quote")
+		artifact := Artifact{Classifier: submatch(libraryString, match, "classifier"), Type: submatch(libraryString, match, "ext")}
+		return callWithClosure(dialect,
+			submatch(libraryString, match, "config"),
+			submatch(libraryString, match, "open"),
+			artifact.variant(dialect, accessor, quote),
+			submatch(libraryString, match, "close"),
+			artifact.statements(dialect, quote, closureOf(following)),
+			following,
+		)
+	})
 
-		err = os.WriteFile(buildFilePath, []byte(updatedContent), 0644)
-		if err != nil {
-			return EmbedResult{}, err
+	libraryMap := &extractor.libraryMap
+	updatedContent = replaceDeclarations(libraryMap, updatedContent, func(s string, following string) (string, int) {
+		match := libraryMap.FindStringSubmatch(s)
+		if version, quoted := unquote(submatch(libraryMap, match, "version")); aliases.isUnresolved(path, version, quoted) {
+			return s, 0
+		}
+		accessor := accessorOf(StrictLibrary{
+			Group:   submatch(libraryMap, match, "group"),
+			Name:    submatch(libraryMap, match, "name"),
+			Version: submatch(libraryMap, match, "version"),
+		})
+		// the quote of the group argument
+		quote := string(s[strings.IndexAny(s, `"'`)])
+		artifact := parseMapArguments(submatch(libraryMap, match, "arguments"))
+		return callWithClosure(dialect,
+			submatch(libraryMap, match, "config"),
+			submatch(libraryMap, match, "open"),
+			artifact.variant(dialect, accessor, quote),
+			submatch(libraryMap, match, "close"),
+			artifact.statements(dialect, quote, closureOf(following)),
+			following,
+		)
+	})
+
+	plugin := &extractor.plugin
+	updatedContent = plugin.ReplaceAllStringFunc(updatedContent, func(s string) string {
+		if isPrecompiledScriptPlugin(path) {
+			// plugins of precompiled script plugins are applied without versions, from the build classpath
+			return s
+		}
+		match := plugin.FindStringSubmatch(s)
+		if version := submatch(plugin, match, "version"); strings.HasPrefix(version, "$") && slices.Contains(aliases.unresolved[path], extractVariableName(version)) {
+			return s
+		}
+		alias := aliases.plugin(Plugin{
+			Id:      submatch(plugin, match, "id"),
+			Version: submatch(plugin, match, "version"),
+		})
+		accessor := pluginAccessor(aliases.pluginCatalog(alias), alias)
+		// alias(...) in both dialects, as Gradle documents it for the plugins { } block
+		return fmt.Sprintf("%salias(%s)", submatch(plugin, match, "leading"), accessor)
+	})
+	return updatedContent
+}
+
+// removeVersionBlocks deletes the version { } blocks of the declarations, as their constraints move to the catalog.
+func removeVersionBlocks(extractor StaticExtractors, content string) string {
+	ends := make([]int, 0)
+	for _, re := range []*regexp.Regexp{&extractor.libraryString, &extractor.libraryMap} {
+		for _, location := range re.FindAllStringSubmatchIndex(content, -1) {
+			version := ""
+			if index := 2 * re.SubexpIndex("version"); location[index] >= 0 {
+				version, _ = unquote(content[location[index]:location[index+1]])
+			}
+			if declaredRichVersion(content, location[1], version) != nil {
+				ends = append(ends, location[1])
+			}
 		}
 	}
-	WrittenInKotlin := strings.HasSuffix(buildFilePaths[0], ".kts")
-	return EmbedResult{UpdatedBuildSrc, WrittenInKotlin}, nil
+	slices.Sort(ends)
+	for i := len(ends) - 1; i >= 0; i-- {
+		content = removeVersionBlock(content, ends[i])
+	}
+	return content
 }
 
 func searchLatestVersions(catalog VersionCatalog) {
@@ -432,64 +637,239 @@ func searchMaven(group, name string) string {
 	return data.Response.Docs[0].Version
 }
 
-func extractVersionCatalog(catalog VersionCatalog, buildFilePaths []string, variableDefFilePaths []string) (VersionCatalog, error) {
+// VersionVariable is a version variable whose value has been consumed into the catalog.
+type VersionVariable struct {
+	Name string
+	// Path is the build file or property file defining the variable.
+	Path string
+	// Key is the catalog entry the value moved to, which differs from Name when the name has several values.
+	// It is empty if the value lost a version conflict and the catalog does not hold it.
+	Key string
+}
+
+// versionKeyFor returns a catalog key for a value of the variable name not cataloged yet.
+// Another value of the same name is suffixed with the project defining it, e.g. okhttpVersion-app
+func versionKeyFor(name string, source string, root string, taken map[string]bool) string {
+	if !taken[name] {
+		return name
+	}
+	suffix := "root"
+	if relative, err := filepath.Rel(root, filepath.Dir(source)); err == nil && relative != "." {
+		suffix = safeKey(filepath.ToSlash(relative))
+	}
+	key := name + "-" + suffix
+	for i := 2; taken[key]; i++ {
+		key = fmt.Sprintf("%s-%s%d", name, suffix, i)
+	}
+	return key
+}
+
+// usageLine returns the line the variable name is first used on in content, or 0 if it cannot be told.
+func usageLine(content string, name string) int {
+	for _, prefix := range []string{`\$\{?`, `\b`} {
+		for _, candidate := range []string{name, strings.ReplaceAll(name, "_", ".")} {
+			location := regexp.MustCompile(prefix + regexp.QuoteMeta(candidate) + `\b`).FindStringIndex(content)
+			if location != nil {
+				return lineAt(content, location[0])
+			}
+		}
+	}
+	// a property read by an expression, e.g. ${property("x")} for project_x
+	if _, property, ok := strings.Cut(name, "_"); ok {
+		if location := regexp.MustCompile(`["']` + regexp.QuoteMeta(property) + `["']`).FindStringIndex(content); location != nil {
+			return lineAt(content, location[0])
+		}
+	}
+	return 0
+}
+
+// ExtractOptions controls how the scanned declarations are turned into catalog entries.
+type ExtractOptions struct {
+	ConflictStrategy ConflictStrategy
+	MergePolicy      MergePolicy
+	// CatalogName is the accessor name build files are rewritten to, e.g. libs
+	CatalogName string
+}
+
+// Extraction is the outcome of scanning build files into a catalog.
+type Extraction struct {
+	Catalog VersionCatalog
+	// Variables are the version variables whose values moved into the catalog.
+	Variables []VersionVariable
+	Aliases   Aliases
+	// Configurations lists the configurations each "group:name" is declared in.
+	Configurations map[string][]string
+}
+
+func extractVersionCatalog(catalog VersionCatalog, buildFilePaths []string, properties *PropertyFiles, objects DependencyObjects, options ExtractOptions, report *Report) (Extraction, error) {
 	extractor := getStaticExtractors()
+	consumed := make([]VersionVariable, 0)
+
+	symbols, err := newSymbolTable(buildFilePaths, properties)
+	if err != nil {
+		return Extraction{}, err
+	}
+	symbols.addObjects(objects)
 
 	versionsAggregated := make(Versions, 0)
 	librariesAggregated := make([]StrictLibrary, 0)
 	pluginsAggregated := make([]Plugin, 0)
+	// keys maps the name of a definition and the value it resolves to onto its catalog key,
+	// so that a name with different values in different projects gets a key per value
+	keys := make(map[[2]string]string)
+	taken := make(map[string]bool)
+	unresolved := make(map[string][]string)
+	// read tells the keys the rewritten scripts read by themselves, e.g. pinned or managed versions,
+	// which stay even if no library refers to them
+	read := make(map[string]bool)
+	preexisting := slices.Collect(maps.Keys(catalog.Versions))
+	// keyFor returns the key of value for name, cataloging it under preferred, if given, or a key derived from name
+	keyFor := func(name string, value string, source string, preferred string) string {
+		if key, ok := keys[[2]string{name, value}]; ok {
+			return key
+		}
+		key := preferred
+		if key == "" {
+			key = versionKeyFor(name, source, properties.root, taken)
+		}
+		keys[[2]string{name, value}] = key
+		taken[key] = true
+		versionsAggregated[key] = value
+		return key
+	}
 
 	for _, path := range buildFilePaths {
 		bytes, err := os.ReadFile(path)
 		if err != nil {
-			return catalog, err
+			return Extraction{}, err
 		}
 		content := string(bytes)
-		versions, plugins, libraries := extractTemp(extractor, content)
-		librariesAggregated = append(librariesAggregated, libraries...)
-		pluginsAggregated = append(pluginsAggregated, plugins...)
-		maps.Copy(versionsAggregated, versions)
-	}
-
-	if len(versionsAggregated) > 0 {
-		keys := make([]string, 0, len(versionsAggregated))
-		for k := range versionsAggregated {
-			keys = append(keys, k)
+		segments := splitIgnoredSegments(content)
+		report.Skipped = append(report.Skipped, findIgnoredDeclarations(extractor, path, segments)...)
+		text := activeText(segments)
+		versions, plugins, libraries := extractTemp(extractor, text)
+		libraries = append(libraries, findForcedLibraries(text)...)
+		pinned, _ := findPinnedVersions(text)
+		for _, version := range pinned {
+			read[keyFor(version.Name, version.Value, path, "")] = true
 		}
-		versionVariableExtractor := compileVersionVariableExtractor(keys)
 
-		// two-path since version variable may be defined in other files
-		for _, path := range buildFilePaths {
-			bytes, err := os.ReadFile(path)
+		renamed := make(map[string]string)
+		for _, name := range slices.Sorted(maps.Keys(versions)) {
+			resolution, ok, err := symbols.resolve(path, name)
 			if err != nil {
-				return catalog, err
+				return Extraction{}, err
+			}
+			if !ok {
+				unresolved[path] = append(unresolved[path], name)
+				report.Unresolved = append(report.Unresolved, UnresolvedVariable{Path: path, Line: usageLine(content, name), Name: name})
+				continue
+			}
+			// entries are named after the definition, so that ${rootProject.ext.x} and $x share one
+			defined := resolution.Source.Name
+			if strings.Contains(defined, ".") {
+				defined = name
+			}
+			preferred := ""
+			if member := objectMemberKey(resolution.Source.Name); strings.Contains(resolution.Source.Name, ".") && catalog.Versions[member] == resolution.Value {
+				// an entry of an ext map, cataloged along with the map
+				preferred = member
+			}
+			key := keyFor(defined, resolution.Value, resolution.Source.Path, preferred)
+			renamed[name] = key
+			variable := VersionVariable{Name: resolution.Source.Name, Path: resolution.Source.Path, Key: key}
+			// entries of ext maps, e.g. versions.okhttp, are retired with their maps
+			if !strings.Contains(variable.Name, ".") && !slices.Contains(consumed, variable) {
+				consumed = append(consumed, variable)
 			}
-			content := string(bytes)
-			extractVersionVariables(versionsAggregated, versionVariableExtractor, content)
 		}
 
-		versionVariableInPropertyFileExtractor := compileVersionVariableInPropertyFileExtractor(keys)
-		for _, path := range variableDefFilePaths {
-			bytes, err := os.ReadFile(path)
-			if err != nil {
-				// Property file may not exist, so we can skip it
-				continue
+		for _, lib := range libraries {
+			lib.Path = path
+			if name, ok := strings.CutPrefix(lib.Version, "$"); ok {
+				if _, resolved := renamed[name]; !resolved {
+					continue
+				}
+				lib.Version = "$" + renamed[name]
+			}
+			if lib.SourceSet != "" {
+				report.addSourceSet(lib.coordinate(), lib.SourceSet)
 			}
-			content := string(bytes)
-			versionsInPropertyFile := extractVersionInPropertyFile(versionVariableInPropertyFileExtractor, content)
-			maps.Copy(versionsAggregated, versionsInPropertyFile)
+			librariesAggregated = append(librariesAggregated, lib)
+		}
+		for _, target := range findSubstitutionTargets(text) {
+			target.Path = path
+			key := keyFor(catalogSafeKey(target), target.Version, path, "")
+			read[key] = true
+			target.Version = "$" + key
+			librariesAggregated = append(librariesAggregated, target)
+		}
+		for _, managed := range findManagedVersions(text) {
+			key := keyFor(managed.Name, managed.Value, path, "")
+			read[key] = true
+			for _, lib := range managed.Libraries {
+				lib.Path = path
+				lib.Version = "$" + key
+				librariesAggregated = append(librariesAggregated, lib)
+			}
+		}
+		for _, plugin := range plugins {
+			if ref, ok := plugin.Version.(LooseLibrary); ok {
+				name, _ := ref["ref"].(string)
+				if _, resolved := renamed[name]; !resolved {
+					continue
+				}
+				plugin.Version = LooseLibrary{"ref": renamed[name]}
+			}
+			pluginsAggregated = append(pluginsAggregated, plugin)
 		}
 	}
 
-	if len(pluginsAggregated) > 0 {
-		for _, plugin := range pluginsAggregated {
-			key := catalogSafeKeyPlugin(plugin)
-			catalog.Plugins[key] = plugin
+	merger := catalogMerger{policy: options.MergePolicy, report: report}
+	for _, key := range slices.Sorted(maps.Keys(versionsAggregated)) {
+		mergeVersionValue(catalog, merger, key, versionsAggregated[key])
+	}
+	aliases, err := updateCatalog(catalog, librariesAggregated, options, merger)
+	if err != nil {
+		return Extraction{}, err
+	}
+	aliases.unresolved = unresolved
+	aliases.versionKeys = keys
+	updateCatalogPlugins(catalog, pluginsAggregated, aliases, merger)
+	if err := merger.err(); err != nil {
+		return Extraction{}, err
+	}
+
+	// the version of a variable may lose a conflict, leaving its entry unused
+	referenced := make(map[string]bool)
+	for _, lib := range catalog.Libraries {
+		if ref, ok := versionRefOf(lib["version"]); ok {
+			referenced[ref] = true
+		}
+	}
+	for _, plugin := range catalog.Plugins {
+		if ref, ok := versionRefOf(plugin.Version); ok {
+			referenced[ref] = true
+		}
+	}
+	for key := range versionsAggregated {
+		if referenced[key] || read[key] || slices.Contains(preexisting, key) {
+			continue
+		}
+		delete(catalog.Versions, key)
+		for i := range consumed {
+			if consumed[i].Key == key {
+				consumed[i].Key = ""
+			}
 		}
 	}
 
-	maps.Copy(catalog.Versions, versionsAggregated)
-	updateCatalog(catalog, librariesAggregated)
+	configurations := make(map[string][]string)
+	for _, lib := range librariesAggregated {
+		if !slices.Contains(configurations[lib.coordinate()], lib.Configuration) {
+			configurations[lib.coordinate()] = append(configurations[lib.coordinate()], lib.Configuration)
+		}
+	}
 
-	return catalog, nil
+	return Extraction{Catalog: catalog, Variables: consumed, Aliases: aliases, Configurations: configurations}, nil
 }
@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// MavenProject is what is read of a pom.xml.
+type MavenProject struct {
+	Parent              *MavenParent      `xml:"parent"`
+	GroupId             string            `xml:"groupId"`
+	ArtifactId          string            `xml:"artifactId"`
+	Version             string            `xml:"version"`
+	Properties          MavenProperties   `xml:"properties"`
+	Modules             []string          `xml:"modules>module"`
+	ManagedDependencies []MavenDependency `xml:"dependencyManagement>dependencies>dependency"`
+	Dependencies        []MavenDependency `xml:"dependencies>dependency"`
+	ManagedPlugins      []MavenPlugin     `xml:"build>pluginManagement>plugins>plugin"`
+	Plugins             []MavenPlugin     `xml:"build>plugins>plugin"`
+
+	// path is the pom.xml, and parent the project inherited from when it is found on disk
+	path    string
+	content string
+	parent  *MavenProject
+}
+
+type MavenParent struct {
+	GroupId    string `xml:"groupId"`
+	ArtifactId string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	// RelativePath is nil when omitted, which means ../pom.xml, and empty for <relativePath/>, which means none
+	RelativePath *string `xml:"relativePath"`
+}
+
+type MavenDependency struct {
+	GroupId    string `xml:"groupId"`
+	ArtifactId string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Type       string `xml:"type"`
+	Scope      string `xml:"scope"`
+}
+
+type MavenPlugin struct {
+	GroupId    string `xml:"groupId"`
+	ArtifactId string `xml:"artifactId"`
+	Version    string `xml:"version"`
+}
+
+// MavenProperties are the <properties> of a pom.xml, keyed by element name.
+type MavenProperties map[string]string
+
+func (p *MavenProperties) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	*p = make(MavenProperties)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			var value string
+			if err := decoder.DecodeElement(&value, &element); err != nil {
+				return err
+			}
+			(*p)[element.Name.Local] = strings.TrimSpace(value)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// the Gradle plugins known to be released along with a Maven plugin, under the same versions
+var gradlePluginsOfMaven = map[string]string{
+	"org.springframework.boot:spring-boot-maven-plugin":        "org.springframework.boot",
+	"org.jetbrains.kotlin:kotlin-maven-plugin":                 "org.jetbrains.kotlin.jvm",
+	"org.graalvm.buildtools:native-maven-plugin":               "org.graalvm.buildtools.native",
+	"io.quarkus:quarkus-maven-plugin":                          "io.quarkus",
+	"io.quarkus.platform:quarkus-maven-plugin":                 "io.quarkus",
+	"com.google.cloud.tools:jib-maven-plugin":                  "com.google.cloud.tools.jib",
+	"org.openapitools:openapi-generator-maven-plugin":          "org.openapi.generator",
+	"org.jetbrains.dokka:dokka-maven-plugin":                   "org.jetbrains.dokka",
+	"org.jetbrains.kotlinx:kover-maven-plugin":                 "org.jetbrains.kotlinx.kover",
+	"org.jooq:jooq-codegen-maven":                              "org.jooq.jooq-codegen-gradle",
+	"org.flywaydb:flyway-maven-plugin":                         "org.flywaydb.flyway",
+	"org.hibernate.orm.tooling:hibernate-enhance-maven-plugin": "org.hibernate.orm",
+}
+
+// the configurations of Gradle matching the scopes of Maven
+var configurationsOfScopes = map[string]string{
+	"":         "implementation",
+	"compile":  "implementation",
+	"provided": "compileOnly",
+	"runtime":  "runtimeOnly",
+	"test":     "testImplementation",
+	"system":   "compileOnly",
+}
+
+var mavenPropertyReferenceExtractor = regexp.MustCompile(`\$\{([^}]+)}`)
+
+// readMavenReactor reads the pom.xml in root and the modules it aggregates, recursively, with the parents they inherit from.
+func readMavenReactor(root string) ([]*MavenProject, error) {
+	loaded := make(map[string]*MavenProject)
+	reactor := make([]*MavenProject, 0)
+	var load func(path string) (*MavenProject, error)
+	load = func(path string) (*MavenProject, error) {
+		if project, ok := loaded[path]; ok {
+			return project, nil
+		}
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		project := &MavenProject{path: path, content: string(bytes)}
+		if err := xml.Unmarshal(bytes, project); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		loaded[path] = project
+
+		if project.Parent != nil {
+			relative := "../pom.xml"
+			if project.Parent.RelativePath != nil {
+				relative = strings.TrimSpace(*project.Parent.RelativePath)
+			}
+			if parentPath, ok := mavenPomPath(filepath.Dir(path), relative); ok {
+				parent, err := load(parentPath)
+				if err != nil {
+					return nil, err
+				}
+				if parent.ArtifactId == project.Parent.ArtifactId {
+					project.parent = parent
+				}
+			}
+			if project.parent == nil {
+				fmt.Printf("NOTICE: The parent %s:%s of %s is not in the build, its properties and managed versions are not inherited.%s",
+					project.Parent.GroupId, project.Parent.ArtifactId, path, LineBreak)
+			}
+		}
+		return project, nil
+	}
+
+	var aggregate func(path string) error
+	aggregate = func(path string) error {
+		project, err := load(path)
+		if err != nil {
+			return err
+		}
+		for _, member := range reactor {
+			if member == project {
+				return nil
+			}
+		}
+		reactor = append(reactor, project)
+		for _, module := range project.Modules {
+			if modulePath, ok := mavenPomPath(filepath.Dir(path), strings.TrimSpace(module)); ok {
+				if err := aggregate(modulePath); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := aggregate(filepath.Join(root, "pom.xml")); err != nil {
+		return nil, err
+	}
+	return reactor, nil
+}
+
+// mavenPomPath returns the pom.xml a relative path given in dir points to, which may be a directory holding it.
+func mavenPomPath(dir string, relative string) (string, bool) {
+	if relative == "" {
+		return "", false
+	}
+	path := filepath.Join(dir, filepath.FromSlash(relative))
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, "pom.xml")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+func (p *MavenProject) groupId() string {
+	if p.GroupId == "" && p.Parent != nil {
+		return p.Parent.GroupId
+	}
+	return p.GroupId
+}
+
+func (p *MavenProject) version() string {
+	if p.Version == "" && p.Parent != nil {
+		return p.Parent.Version
+	}
+	return p.Version
+}
+
+// property returns the raw value of a property as inherited by the project, and the pom.xml defining it.
+func (p *MavenProject) property(name string) (string, string, bool) {
+	switch name {
+	case "project.version", "pom.version", "version":
+		return p.version(), p.path, p.version() != ""
+	case "project.groupId", "pom.groupId", "groupId":
+		return p.groupId(), p.path, true
+	case "project.artifactId", "pom.artifactId", "artifactId":
+		return p.ArtifactId, p.path, true
+	case "project.parent.version", "parent.version":
+		if p.Parent != nil {
+			return p.Parent.Version, p.path, true
+		}
+	}
+	return p.definedProperty(name)
+}
+
+// definedProperty returns the raw value of a property of the <properties> of the project or of its parents,
+// and the pom.xml defining it.
+func (p *MavenProject) definedProperty(name string) (string, string, bool) {
+	for project := p; project != nil; project = project.parent {
+		if value, ok := project.Properties[name]; ok {
+			return value, project.path, true
+		}
+	}
+	return "", "", false
+}
+
+// interpolate expands the ${...} references of value the way Maven does for the project,
+// and returns the references it cannot resolve.
+func (p *MavenProject) interpolate(value string) (string, []string) {
+	unresolved := make([]string, 0)
+	for depth := 0; depth < maxSymbolDepth && strings.Contains(value, "${"); depth++ {
+		value = mavenPropertyReferenceExtractor.ReplaceAllStringFunc(value, func(reference string) string {
+			name := reference[2 : len(reference)-1]
+			resolved, _, ok := p.property(name)
+			if !ok {
+				unresolved = append(unresolved, name)
+				return reference
+			}
+			return resolved
+		})
+		if len(unresolved) > 0 {
+			break
+		}
+	}
+	return value, unresolved
+}
+
+// managedVersion returns the raw version the dependencyManagement of the project or of its parents gives a module.
+func (p *MavenProject) managedVersion(groupId string, artifactId string) string {
+	for project := p; project != nil; project = project.parent {
+		for _, dependency := range project.ManagedDependencies {
+			if g, _ := p.interpolate(dependency.GroupId); g == groupId && dependency.ArtifactId == artifactId {
+				return dependency.Version
+			}
+		}
+	}
+	return ""
+}
+
+// extractMavenCatalog collects the dependencies, managed dependencies and plugins of a reactor into catalog.
+// A version given by a property, e.g. ${jackson.version}, is cataloged under a [versions] key named after it.
+func extractMavenCatalog(catalog VersionCatalog, reactor []*MavenProject, root string, options ExtractOptions, report *Report) (VersionCatalog, error) {
+	reactorModules := make(map[string]bool)
+	for _, project := range reactor {
+		reactorModules[project.groupId()+":"+project.ArtifactId] = true
+	}
+
+	versionsAggregated := make(Versions)
+	keys := make(map[[2]string]string)
+	taken := make(map[string]bool)
+	// versionOf turns the raw version of a module of the project into a catalog version
+	versionOf := func(project *MavenProject, raw string) (string, bool) {
+		if raw == "" {
+			return "FIXME", true
+		}
+		if match := mavenPropertyReferenceExtractor.FindStringSubmatch(raw); match != nil && match[0] == raw {
+			if _, source, ok := project.definedProperty(match[1]); ok {
+				value, unresolved := project.interpolate(raw)
+				if len(unresolved) == 0 {
+					name := safeKey(match[1])
+					key, ok := keys[[2]string{name, value}]
+					if !ok {
+						key = versionKeyFor(name, source, root, taken)
+						keys[[2]string{name, value}] = key
+						taken[key] = true
+						versionsAggregated[key] = value
+					}
+					return "$" + key, true
+				}
+			}
+		}
+		value, unresolved := project.interpolate(raw)
+		for _, name := range unresolved {
+			report.Unresolved = append(report.Unresolved, UnresolvedVariable{Path: project.path, Line: usageLine(project.content, name), Name: name})
+		}
+		return value, len(unresolved) == 0
+	}
+
+	libraries := make([]StrictLibrary, 0)
+	plugins := make([]Plugin, 0)
+	for _, project := range reactor {
+		add := func(dependency MavenDependency, managed bool) {
+			groupId, _ := project.interpolate(dependency.GroupId)
+			if reactorModules[groupId+":"+dependency.ArtifactId] {
+				// a project of the build, which Gradle depends on with project(...)
+				return
+			}
+			raw := dependency.Version
+			if raw == "" && !managed {
+				raw = project.managedVersion(groupId, dependency.ArtifactId)
+			}
+			version, ok := versionOf(project, raw)
+			if !ok {
+				return
+			}
+			configuration := configurationsOfScopes[dependency.Scope]
+			if dependency.Scope == "import" {
+				configuration = "platform"
+			} else if managed {
+				configuration = ""
+			}
+			libraries = append(libraries, StrictLibrary{
+				Group:         groupId,
+				Name:          dependency.ArtifactId,
+				Version:       version,
+				Path:          project.path,
+				Configuration: configuration,
+			})
+		}
+		for _, dependency := range project.ManagedDependencies {
+			add(dependency, true)
+		}
+		for _, dependency := range project.Dependencies {
+			add(dependency, false)
+		}
+
+		for _, plugin := range append(project.ManagedPlugins, project.Plugins...) {
+			groupId := plugin.GroupId
+			if groupId == "" {
+				groupId = "org.apache.maven.plugins"
+			}
+			id := gradlePluginsOfMaven[groupId+":"+plugin.ArtifactId]
+			if id == "" || plugin.Version == "" {
+				continue
+			}
+			version, ok := versionOf(project, plugin.Version)
+			if !ok {
+				continue
+			}
+			plugins = append(plugins, Plugin{Id: id, Version: toCatalogVersion(version)})
+		}
+	}
+
+	merger := catalogMerger{policy: options.MergePolicy, report: report}
+	for _, key := range slices.Sorted(maps.Keys(versionsAggregated)) {
+		mergeVersionValue(catalog, merger, key, versionsAggregated[key])
+	}
+	aliases, err := updateCatalog(catalog, libraries, options, merger)
+	if err != nil {
+		return catalog, err
+	}
+	updateCatalogPlugins(catalog, plugins, aliases, merger)
+	if err := merger.err(); err != nil {
+		return catalog, err
+	}
+	return catalog, nil
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MergePolicy decides between a value already in the catalog and the value scanned from build files.
+type MergePolicy string
+
+const (
+	MergePreferScanned    MergePolicy = "prefer-scanned"
+	MergeKeepExisting     MergePolicy = "keep-existing"
+	MergeFailOnDifference MergePolicy = "fail-on-difference"
+)
+
+var mergePolicies = []MergePolicy{MergePreferScanned, MergeKeepExisting, MergeFailOnDifference}
+
+func parseMergePolicy(value string) (MergePolicy, error) {
+	policy := MergePolicy(value)
+	if !slices.Contains(mergePolicies, policy) {
+		return "", fmt.Errorf("unknown merge policy %q, must be one of %v", value, mergePolicies)
+	}
+	return policy, nil
+}
+
+// CatalogDifference is a catalog value that differs from the scanned one.
+type CatalogDifference struct {
+	Entry      string
+	Existing   string
+	Scanned    string
+	Resolution string
+}
+
+func (d CatalogDifference) String() string {
+	return fmt.Sprintf("%s: %s (existing) vs %s (scanned), %s", d.Entry, d.Existing, d.Scanned, d.Resolution)
+}
+
+const keptRichVersion = "kept the rich version"
+
+type catalogMerger struct {
+	policy MergePolicy
+	report *Report
+}
+
+// merge returns the value to be written for entry.
+// A missing or FIXME scanned value never replaces an existing one,
+// and a rich version is never downgraded to a plain one or a lower rich one.
+func (m catalogMerger) merge(entry string, existing any, scanned any) any {
+	if existing == nil || isMissingVersion(existing) {
+		return scanned
+	}
+	if isMissingVersion(scanned) {
+		return existing
+	}
+	existingText := describeVersion(existing)
+	scannedText := describeVersion(scanned)
+	if existingText == scannedText {
+		return existing
+	}
+
+	difference := CatalogDifference{Entry: entry, Existing: existingText, Scanned: scannedText}
+	merged := existing
+	switch {
+	case isRichVersion(existing) && !isRichVersion(scanned),
+		isRichVersion(existing) && compareVersions(plainVersion(scanned), plainVersion(existing)) < 0:
+		difference.Resolution = keptRichVersion
+	case m.policy == MergeKeepExisting:
+		difference.Resolution = "kept existing"
+	case m.policy == MergeFailOnDifference:
+		difference.Resolution = "failed"
+	default:
+		difference.Resolution = "overridden"
+		merged = scanned
+	}
+	m.report.Differences = append(m.report.Differences, difference)
+	return merged
+}
+
+// err fails on the differences the policy could not resolve, leaving out the rich versions kept whatever the policy.
+func (m catalogMerger) err() error {
+	if m.policy != MergeFailOnDifference {
+		return nil
+	}
+	descriptions := make([]string, 0)
+	for _, difference := range m.report.Differences {
+		if difference.Resolution != keptRichVersion {
+			descriptions = append(descriptions, difference.String())
+		}
+	}
+	if len(descriptions) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the existing catalog differs from build files:%s  %s", LineBreak, strings.Join(descriptions, LineBreak+"  "))
+}
+
+func isMissingVersion(version any) bool {
+	if s, ok := version.(string); ok {
+		return s == "" || s == "FIXME"
+	}
+	return version == nil
+}
+
+func isRichVersion(version any) bool {
+	if loose, ok := version.(LooseLibrary); ok {
+		_, isRef := versionRefOf(loose)
+		return !isRef
+	}
+	return false
+}
+
+func describeVersion(version any) string {
+	if s, ok := version.(string); ok {
+		return strconv.Quote(s)
+	}
+	return strings.TrimPrefix(writeVersionEntry(version), ", ")
+}
@@ -0,0 +1,538 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ObjectMemberKind is the catalog section a constant of a dependency object moves to.
+type ObjectMemberKind string
+
+const (
+	ObjectVersion ObjectMemberKind = "version"
+	ObjectLibrary ObjectMemberKind = "library"
+)
+
+// ObjectMember is a `const val` of a dependency object, or an entry of an ext map.
+type ObjectMember struct {
+	// Reference is how scripts refer to the constant, e.g. Libs.okhttp
+	Reference string
+	Value     string
+	// Kind is empty if the constant is not migrated.
+	Kind ObjectMemberKind
+	// Key is the [versions] key or the [libraries] alias the constant moves to.
+	Key     string
+	Library StrictLibrary
+	// resolved is Value with the constants it interpolates substituted.
+	resolved string
+	// offset is where the line declaring the constant starts.
+	offset     int
+	deprecated bool
+	dialect    Dialect
+}
+
+// DependencyObjectFile is a Kotlin source declaring dependencies as constants of objects, e.g.
+//
+//	object Libs { const val okhttp = "com.squareup.okhttp3:okhttp:${Versions.okhttp}" }
+//
+// or a Groovy script declaring them in ext maps, e.g.
+//
+//	ext.deps = [okhttp: "com.squareup.okhttp3:okhttp:$versions.okhttp"]
+type DependencyObjectFile struct {
+	Path    string
+	Dialect Dialect
+	// Objects are the names of the top-level objects or ext maps of the file.
+	Objects []string
+	// declarations are where the top-level objects or ext maps are declared, in the order of Objects.
+	declarations [][2]int
+	Members      []ObjectMember
+	// standalone tells whether the file declares nothing but its objects.
+	standalone bool
+}
+
+func (f DependencyObjectFile) isMigrated() bool {
+	return slices.ContainsFunc(f.Members, func(member ObjectMember) bool { return member.Kind != "" })
+}
+
+func (f DependencyObjectFile) isFullyMigrated() bool {
+	return !slices.ContainsFunc(f.Members, func(member ObjectMember) bool { return member.Kind == "" })
+}
+
+// DependencyObjects are the dependency objects of buildSrc and included builds and the ext maps of build scripts,
+// along with all the Kotlin sources of those builds, which may still use the objects.
+type DependencyObjects struct {
+	Files   []DependencyObjectFile
+	Sources []string
+}
+
+var kotlinSourceDirectories = []string{filepath.Join("src", "main", "kotlin")}
+
+var objectExtractor = regexp.MustCompile(`(?m)^[ \t]*(?:(?:public|internal)[ \t]+)?object[ \t]+(\w+)\s*\{`)
+var constantExtractor = regexp.MustCompile(`(?m)^[ \t]*(?:(?:public|internal|private)[ \t]+)?const[ \t]+val[ \t]+(\w+)\s*(?::\s*String\s*)?=\s*"([^"\\\r\n]*)"[ \t]*;?[ \t]*$`)
+var kotlinHeaderExtractor = regexp.MustCompile(`(?m)^[ \t]*(?:package|import|@file:)[^\r\n]*$`)
+var kotlinInterpolationExtractor = regexp.MustCompile(`\$\{([\w.]+)}|\$(\w+)`)
+
+// Groovy strings interpolate property paths without braces, e.g. "$versions.okhttp"
+var groovyInterpolationExtractor = regexp.MustCompile(`\$\{\s*([\w.]+)\s*}|\$([\w.]*\w)`)
+var objectLibraryExtractor = regexp.MustCompile(`^([^:\s]+):([^:\s]+)(?::([^:\s]+))?$`)
+var versionValueExtractor = regexp.MustCompile(`^[0-9][\w.+-]*$`)
+
+func isKotlinSource(name string) bool {
+	return strings.HasSuffix(name, ".kt")
+}
+
+// findDependencyObjects parses the dependency objects of the builds included by the build in root
+// and the ext maps of the given build scripts, and decides which of their constants move to the catalog.
+func findDependencyObjects(root string, buildFilePaths []string) (DependencyObjects, error) {
+	sources, err := findIncludedBuildSources(root, kotlinSourceDirectories, isKotlinSource)
+	if err != nil {
+		return DependencyObjects{}, err
+	}
+	objects := DependencyObjects{Files: make([]DependencyObjectFile, 0), Sources: sources}
+	for _, path := range sources {
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return DependencyObjects{}, err
+		}
+		file := parseDependencyObjects(path, string(bytes))
+		if len(file.Members) > 0 {
+			objects.Files = append(objects.Files, file)
+		}
+	}
+	for _, path := range buildFilePaths {
+		if dialectOf(path) != Groovy {
+			continue
+		}
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return DependencyObjects{}, err
+		}
+		file := parseExtMaps(path, string(bytes))
+		if len(file.Members) > 0 {
+			objects.Files = append(objects.Files, file)
+		}
+	}
+	objects.classify()
+	return objects, nil
+}
+
+func parseDependencyObjects(path string, content string) DependencyObjectFile {
+	file := DependencyObjectFile{Path: path, Dialect: Kotlin, Objects: make([]string, 0), Members: make([]ObjectMember, 0)}
+	stripped := stripComments(content)
+	objectRanges := parseObjectBody(content, stripped, 0, len(stripped), "", &file)
+	var rest strings.Builder
+	last := 0
+	for _, objectRange := range objectRanges {
+		rest.WriteString(stripped[last:objectRange[0]])
+		last = objectRange[1]
+	}
+	rest.WriteString(stripped[min(last, len(stripped)):])
+	file.standalone = strings.TrimSpace(kotlinHeaderExtractor.ReplaceAllString(rest.String(), "")) == ""
+	return file
+}
+
+// parseObjectBody collects the constants of the objects between from and to, prefixing their names
+// with the enclosing objects, and returns where the objects directly in there start and end.
+func parseObjectBody(content string, stripped string, from int, to int, prefix string, file *DependencyObjectFile) [][2]int {
+	objectRanges := make([][2]int, 0)
+	for pos := from; pos < to; {
+		location := objectExtractor.FindStringSubmatchIndex(stripped[pos:to])
+		if location == nil {
+			break
+		}
+		name := stripped[pos+location[2] : pos+location[3]]
+		open := pos + location[1] - 1
+		body := blockBody(stripped, open)
+		end := min(open+len(body)+2, len(stripped))
+		if prefix == "" {
+			file.Objects = append(file.Objects, name)
+			file.declarations = append(file.declarations, [2]int{pos + location[0], end})
+		}
+		parseObjectBody(content, stripped, open+1, open+1+len(body), prefix+name+".", file)
+		objectRanges = append(objectRanges, [2]int{pos + location[0], end})
+		pos = end
+	}
+	if prefix == "" {
+		// constants outside objects are referenced by their bare name, which is too ambiguous to rewrite
+		return objectRanges
+	}
+	for _, location := range constantExtractor.FindAllStringSubmatchIndex(stripped[from:to], -1) {
+		start := from + location[0]
+		nested := slices.ContainsFunc(objectRanges, func(objectRange [2]int) bool {
+			return objectRange[0] <= start && start < objectRange[1]
+		})
+		if nested {
+			continue
+		}
+		previousLine := content[strings.LastIndexByte(strings.TrimRight(content[:start], " \t\r\n"), '\n')+1 : start]
+		file.Members = append(file.Members, ObjectMember{
+			Reference:  prefix + content[from+location[2]:from+location[3]],
+			Value:      content[from+location[4] : from+location[5]],
+			offset:     start,
+			deprecated: strings.Contains(previousLine, "@Deprecated"),
+			dialect:    Kotlin,
+		})
+	}
+	return objectRanges
+}
+
+// objectMemberKey turns the reference to a constant into a catalog key, dropping the top-level object,
+// e.g. Libs.AndroidX.coreKtx -> androidX-coreKtx and Versions.OKHTTP_LOGGING -> okhttp-logging
+func objectMemberKey(reference string) string {
+	segments := strings.Split(reference, ".")[1:]
+	for i, segment := range segments {
+		if strings.ToUpper(segment) == segment {
+			segment = strings.ToLower(segment)
+		} else {
+			runes := []rune(segment)
+			runes[0] = unicode.ToLower(runes[0])
+			segment = string(runes)
+		}
+		segments[i] = strings.Trim(strings.ReplaceAll(segment, "_", "-"), "-")
+	}
+	return strings.Join(segments, "-")
+}
+
+// classify decides which constants are libraries and which are versions.
+// A library whose version is a single constant refers to it as a [versions] entry.
+func (o DependencyObjects) classify() {
+	members := make(map[string]*ObjectMember)
+	ordered := make([]*ObjectMember, 0)
+	for i := range o.Files {
+		for j := range o.Files[i].Members {
+			member := &o.Files[i].Members[j]
+			members[member.Reference] = member
+			ordered = append(ordered, member)
+		}
+	}
+	lookup := func(member *ObjectMember, name string) *ObjectMember {
+		if found, ok := members[name]; ok {
+			return found
+		}
+		scope := member.Reference[:strings.LastIndexByte(member.Reference, '.')+1]
+		return members[scope+name]
+	}
+	var resolve func(member *ObjectMember, text string, depth int) (string, bool)
+	resolve = func(member *ObjectMember, text string, depth int) (string, bool) {
+		ok := true
+		resolved := member.interpolations().ReplaceAllStringFunc(text, func(s string) string {
+			target := lookup(member, member.interpolatedName(s))
+			if target == nil || depth > len(ordered) {
+				ok = false
+				return s
+			}
+			value, resolvedOK := resolve(target, target.Value, depth+1)
+			ok = ok && resolvedOK
+			return value
+		})
+		return resolved, ok
+	}
+
+	for _, member := range ordered {
+		match := objectLibraryExtractor.FindStringSubmatch(member.Value)
+		if match == nil {
+			continue
+		}
+		group, groupOK := resolve(member, match[1], 0)
+		name, nameOK := resolve(member, match[2], 0)
+		version := "FIXME"
+		versionOK := true
+		if raw := match[3]; raw != "" {
+			version, versionOK = resolve(member, raw, 0)
+			if whole := member.interpolations().FindString(raw); versionOK && whole == raw {
+				target := lookup(member, member.interpolatedName(raw))
+				if !objectLibraryExtractor.MatchString(target.Value) {
+					target.Kind = ObjectVersion
+					target.Key = objectMemberKey(target.Reference)
+					version = "$" + target.Key
+				}
+			}
+		}
+		if !groupOK || !nameOK || !versionOK {
+			fmt.Printf("NOTICE: %s refers to an unknown constant, keeping it.%s", member.Reference, LineBreak)
+			continue
+		}
+		member.Kind = ObjectLibrary
+		member.Key = objectMemberKey(member.Reference)
+		member.Library = StrictLibrary{Group: group, Name: name, Version: version}
+	}
+
+	for _, member := range ordered {
+		if member.Kind == ObjectLibrary {
+			continue
+		}
+		resolved, ok := resolve(member, member.Value, 0)
+		if !ok || (member.Kind == "" && !versionValueExtractor.MatchString(resolved)) {
+			member.Kind = ""
+			continue
+		}
+		member.Kind = ObjectVersion
+		member.Key = objectMemberKey(member.Reference)
+		member.resolved = resolved
+	}
+}
+
+func (m ObjectMember) interpolations() *regexp.Regexp {
+	if m.dialect == Groovy {
+		return groovyInterpolationExtractor
+	}
+	return kotlinInterpolationExtractor
+}
+
+// interpolatedName returns the constant an interpolation such as ${Versions.okhttp} refers to.
+func (m ObjectMember) interpolatedName(interpolation string) string {
+	match := m.interpolations().FindStringSubmatch(interpolation)
+	name := cmp.Or(match[1], match[2])
+	if m.dialect == Groovy {
+		name = extQualifierExtractor.ReplaceAllString(name, "")
+	}
+	return name
+}
+
+func (o DependencyObjects) members() []*ObjectMember {
+	members := make([]*ObjectMember, 0)
+	for i := range o.Files {
+		for j := range o.Files[i].Members {
+			if o.Files[i].Members[j].Kind != "" {
+				members = append(members, &o.Files[i].Members[j])
+			}
+		}
+	}
+	return members
+}
+
+// addTo puts the migrated constants into the catalog. A library already in the catalog keeps its alias.
+func (o DependencyObjects) addTo(catalog VersionCatalog, merger catalogMerger) {
+	for _, member := range o.members() {
+		if member.Kind == ObjectVersion {
+			mergeVersionValue(catalog, merger, member.Key, member.resolved)
+		}
+	}
+	existingAliases := indexLibraryAliases(catalog.Libraries)
+	for _, member := range o.members() {
+		if member.Kind != ObjectLibrary {
+			continue
+		}
+		lib := member.Library
+		if alias, ok := existingAliases[lib.coordinate()]; ok {
+			member.Key = alias
+			catalog.Libraries[alias] = reuseLibraryEntry(catalog, merger, alias, resolveVersion(catalog, lib.Version), lib.catalogVersion())
+			continue
+		}
+		if _, taken := catalog.Libraries[member.Key]; taken {
+			member.Key = catalogSafeKey(lib)
+		}
+		catalog.Libraries[member.Key] = LooseLibrary{
+			"group":   lib.Group,
+			"name":    lib.Name,
+			"version": toCatalogVersion(lib.Version),
+		}
+		existingAliases[lib.coordinate()] = member.Key
+	}
+}
+
+// accessor returns the expression build scripts read the migrated constant with.
+func (m ObjectMember) accessor(path string, aliases Aliases, style PrecompiledStyle) string {
+	dialect := dialectOf(path)
+	if m.Kind == ObjectVersion {
+		if usesCatalogAPI(path, style) {
+			return catalogAPIVersion(dialect, aliases.versionCatalog(m.Key), m.Key)
+		}
+		return versionAccessor(aliases.versionCatalog(m.Key), m.Key)
+	}
+	if usesCatalogAPI(path, style) {
+		return catalogAPILibrary(dialect, aliases.libraryCatalog(m.Key), m.Key)
+	}
+	return libraryAccessor(aliases.libraryCatalog(m.Key), m.Key)
+}
+
+// catalogEntry names the catalog entry the constant moved to, e.g. libs.okhttp or libs.versions.okhttp
+func (m ObjectMember) catalogEntry(aliases Aliases) string {
+	if m.Kind == ObjectVersion {
+		return strings.TrimSuffix(versionAccessor(aliases.versionCatalog(m.Key), m.Key), ".get()")
+	}
+	return libraryAccessor(aliases.libraryCatalog(m.Key), m.Key)
+}
+
+func compileObjectReferenceExtractor(member ObjectMember) *regexp.Regexp {
+	if member.dialect == Groovy {
+		return compileExtReferenceExtractor(member.Reference)
+	}
+	return regexp.MustCompile(`(^|[^\w.])` + regexp.QuoteMeta(member.Reference) + `\b`)
+}
+
+// rewriteReferences points the references to migrated constants in build scripts at the catalog
+// and returns the files that changed.
+func (o DependencyObjects) rewriteReferences(buildFilePaths []string, aliases Aliases, style PrecompiledStyle, changes *ChangeSet) ([]string, error) {
+	members := o.members()
+	if len(members) == 0 {
+		return nil, nil
+	}
+	extractors := make([]*regexp.Regexp, len(members))
+	for i, member := range members {
+		extractors[i] = compileObjectReferenceExtractor(*member)
+	}
+	updated := make([]string, 0)
+	for _, path := range buildFilePaths {
+		content, err := changes.read(path)
+		if err != nil {
+			return nil, err
+		}
+		replace := func(text string) string {
+			for i, member := range members {
+				text = extractors[i].ReplaceAllString(text, "${1}"+member.accessor(path, aliases, style))
+			}
+			return text
+		}
+		rewrite := func(text string) string {
+			return rewriteActiveSegments(text, func(text string) string {
+				// the declarations left out of the catalog keep interpolating the constants
+				var builder strings.Builder
+				last := 0
+				for _, declaration := range uncatalogedDeclarations(text, path, aliases) {
+					if declaration[0] < last {
+						continue
+					}
+					builder.WriteString(replace(text[last:declaration[0]]))
+					builder.WriteString(text[declaration[0]:declaration[1]])
+					last = declaration[1]
+				}
+				builder.WriteString(replace(text[last:]))
+				return builder.String()
+			})
+		}
+		// the ext maps themselves keep their values
+		var builder strings.Builder
+		last := 0
+		if dialectOf(path) == Groovy {
+			for _, declaration := range parseExtMaps(path, content).declarations {
+				builder.WriteString(rewrite(content[last:declaration[0]]))
+				builder.WriteString(content[declaration[0]:declaration[1]])
+				last = declaration[1]
+			}
+		}
+		builder.WriteString(rewrite(content[last:]))
+		updatedContent := builder.String()
+		if updatedContent != content {
+			updated = append(updated, path)
+			changes.write(path, updatedContent)
+		}
+	}
+	return updated, nil
+}
+
+// uncatalogedDeclarations returns where the declarations whose version could not be resolved are in text.
+func uncatalogedDeclarations(text string, path string, aliases Aliases) [][2]int {
+	extractor := getStaticExtractors()
+	declarations := make([][2]int, 0)
+	for _, re := range []*regexp.Regexp{&extractor.libraryString, &extractor.libraryMap, &extractor.plugin} {
+		index := 2 * re.SubexpIndex("version")
+		for _, location := range re.FindAllStringSubmatchIndex(text, -1) {
+			if location[index] < 0 {
+				continue
+			}
+			version, quoted := text[location[index]:location[index+1]], true
+			if re == &extractor.libraryMap {
+				version, quoted = unquote(version)
+			}
+			if aliases.isUnresolved(path, version, quoted) {
+				declarations = append(declarations, [2]int{location[0], location[1]})
+			}
+		}
+	}
+	slices.SortFunc(declarations, func(a, b [2]int) int { return cmp.Compare(a[0], b[0]) })
+	return declarations
+}
+
+// retire deletes the files whose constants all moved to the catalog once nothing refers to their objects,
+// and marks the migrated constants of the other files as deprecated.
+func (o DependencyObjects) retire(root string, buildFilePaths []string, aliases Aliases, changes *ChangeSet) error {
+	if err := o.retireExtMaps(root, buildFilePaths, changes); err != nil {
+		return err
+	}
+	removable := make(map[string]bool)
+	for _, file := range o.Files {
+		removable[file.Path] = file.Dialect == Kotlin && file.standalone && file.isFullyMigrated()
+	}
+	objectsOf := make(map[string]*regexp.Regexp)
+	for _, file := range o.Files {
+		objectsOf[file.Path] = regexp.MustCompile(`(^|[^\w.])(?:` + strings.Join(file.Objects, "|") + `)\b`)
+	}
+	readers := slices.Concat(buildFilePaths, o.Sources)
+	for changed := true; changed; {
+		changed = false
+		for _, file := range o.Files {
+			if !removable[file.Path] {
+				continue
+			}
+			for _, reader := range readers {
+				if reader == file.Path || removable[reader] {
+					continue
+				}
+				content, err := changes.read(reader)
+				if os.IsNotExist(err) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				content = kotlinHeaderExtractor.ReplaceAllString(stripComments(content), "")
+				if objectsOf[file.Path].MatchString(content) {
+					fmt.Printf("NOTICE: %s is still referenced from %s, keeping it.%s", file.Path, reader, LineBreak)
+					removable[file.Path] = false
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	for _, file := range o.Files {
+		if file.Dialect != Kotlin || !file.isMigrated() {
+			continue
+		}
+		if removable[file.Path] {
+			changes.remove(file.Path)
+			fmt.Printf("Removed: %s%s", file.Path, LineBreak)
+			if err := removeObjectImports(buildFilePaths, file.Objects, changes); err != nil {
+				return err
+			}
+			continue
+		}
+		content, err := changes.read(file.Path)
+		if err != nil {
+			return err
+		}
+		for i := len(file.Members) - 1; i >= 0; i-- {
+			member := file.Members[i]
+			if member.Kind == "" || member.deprecated {
+				continue
+			}
+			line := content[member.offset:]
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			annotation := fmt.Sprintf("%s@Deprecated(\"Use %s from the version catalog\")%s", indent, member.catalogEntry(aliases), LineBreak)
+			content = content[:member.offset] + annotation + content[member.offset:]
+			fmt.Printf("Deprecated: %s in %s%s", member.Reference, file.Path, LineBreak)
+		}
+		changes.write(file.Path, content)
+	}
+	return nil
+}
+
+func removeObjectImports(buildFilePaths []string, objects []string, changes *ChangeSet) error {
+	importExtractor := regexp.MustCompile(`(?m)^[ \t]*import[ \t]+(?:[\w.]+\.)?(?:` + strings.Join(objects, "|") + `)(?:\.\*)?[ \t]*;?[ \t]*(?:\r?\n|$)`)
+	for _, path := range buildFilePaths {
+		content, err := changes.read(path)
+		if err != nil {
+			return err
+		}
+		changes.write(path, importExtractor.ReplaceAllString(content, ""))
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Qualifiers with a special meaning in Gradle's version ordering.
+// "dev" sorts before any other qualifier, the rest sort after any unknown qualifier in this order.
+// https://docs.gradle.org/current/userguide/single_versions.html#version_ordering
+var specialQualifiers = map[string]int{
+	"dev":      -1,
+	"rc":       1,
+	"snapshot": 2,
+	"final":    3,
+	"ga":       4,
+	"release":  5,
+	"sp":       6,
+}
+
+// compareVersions orders two version strings the way Gradle does.
+func compareVersions(a, b string) int {
+	partsA := splitVersion(a)
+	partsB := splitVersion(b)
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		if c := compareVersionParts(partsA[i], partsB[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(partsA) > len(partsB):
+		return extraPartSign(partsA[len(partsB)])
+	case len(partsA) < len(partsB):
+		return -extraPartSign(partsB[len(partsA)])
+	}
+	return 0
+}
+
+// splitVersion splits on '.', '-', '_', '+' and on transitions between digits and letters.
+func splitVersion(version string) []string {
+	parts := make([]string, 0)
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+		}
+	}
+	var prev rune
+	for i, r := range version {
+		if strings.ContainsRune(".-_+", r) {
+			flush()
+			prev = 0
+			continue
+		}
+		if i > 0 && prev != 0 && unicode.IsDigit(prev) != unicode.IsDigit(r) {
+			flush()
+		}
+		current.WriteRune(r)
+		prev = r
+	}
+	flush()
+	return parts
+}
+
+func compareVersionParts(a, b string) int {
+	numA, errA := strconv.Atoi(a)
+	numB, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return numA - numB
+	case errA == nil:
+		return 1
+	case errB == nil:
+		return -1
+	}
+
+	rankA, specialA := specialQualifiers[strings.ToLower(a)]
+	rankB, specialB := specialQualifiers[strings.ToLower(b)]
+	switch {
+	case specialA && specialB:
+		return rankA - rankB
+	case specialA:
+		return rankA
+	case specialB:
+		return -rankB
+	}
+	return strings.Compare(a, b)
+}
+
+// extraPartSign tells whether a version is higher because of an extra trailing part:
+// 1.0.1 > 1.0 but 1.0-beta < 1.0
+func extraPartSign(part string) int {
+	if _, err := strconv.Atoi(part); err == nil {
+		return 1
+	}
+	return -1
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	ascending := [][2]string{
+		{"1.0", "1.1"},
+		{"1.2", "1.10"},
+		{"1.0", "1.0.1"},
+		{"1.0-beta", "1.0"},
+		{"1.0-dev", "1.0-alpha"},
+		{"1.0-alpha", "1.0-rc"},
+		{"1.0-rc1", "1.0-rc2"},
+		{"1.0-RC", "1.0-SNAPSHOT"},
+		{"1.0-SNAPSHOT", "1.0-FINAL"},
+		{"1.0-GA", "1.0-RELEASE"},
+		{"1.0-RELEASE", "1.0-SP1"},
+		{"1.0.a", "1.0.1"},
+		{"31.1-jre", "33.0.0-jre"},
+	}
+	for _, pair := range ascending {
+		assert.Negative(t, compareVersions(pair[0], pair[1]), "%s < %s", pair[0], pair[1])
+		assert.Positive(t, compareVersions(pair[1], pair[0]), "%s > %s", pair[1], pair[0])
+	}
+	assert.Zero(t, compareVersions("1.0.0", "1-0_0"))
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// PrecompiledStyle is how precompiled script plugins, which get no type-safe catalog accessors, reach a catalog.
+type PrecompiledStyle string
+
+const (
+	// PrecompiledCatalogAPI uses versionCatalogs.named("libs").findLibrary("x").get() and the like.
+	PrecompiledCatalogAPI PrecompiledStyle = "catalog-api"
+	// PrecompiledLibrariesFor keeps libs.x by declaring `val libs = the<LibrariesForLibs>()` in Kotlin scripts,
+	// and puts the generated accessors on the classpath of the build the scripts belong to.
+	PrecompiledLibrariesFor PrecompiledStyle = "libraries-for"
+)
+
+var precompiledStyles = []PrecompiledStyle{PrecompiledCatalogAPI, PrecompiledLibrariesFor}
+
+func parsePrecompiledStyle(value string) (PrecompiledStyle, error) {
+	style := PrecompiledStyle(value)
+	if !slices.Contains(precompiledStyles, style) {
+		return "", fmt.Errorf("unknown precompiled script plugin style %q, must be one of %v", value, precompiledStyles)
+	}
+	return style, nil
+}
+
+// Source directories of precompiled script plugins, relative to the build holding them.
+var precompiledScriptDirectories = []string{
+	filepath.Join("src", "main", "kotlin"),
+	filepath.Join("src", "main", "groovy"),
+}
+
+func isPrecompiledScriptPlugin(path string) bool {
+	slashed := filepath.ToSlash(path)
+	return isBuildScript(path) && (strings.Contains(slashed, "/src/main/kotlin/") || strings.Contains(slashed, "/src/main/groovy/"))
+}
+
+// usesCatalogAPI tells whether the script at path has to reach catalogs through the catalog API.
+// Groovy precompiled scripts always do, as the LibrariesFor workaround is for Kotlin only.
+func usesCatalogAPI(path string, style PrecompiledStyle) bool {
+	if !isPrecompiledScriptPlugin(path) {
+		return false
+	}
+	return style == PrecompiledCatalogAPI || dialectOf(path) == Groovy
+}
+
+func catalogAPI(dialect Dialect, catalog string) string {
+	return fmt.Sprintf("versionCatalogs.named(%s)", dialect.quote(catalog, "'"))
+}
+
+// catalogAPILibrary returns the catalog API expression of a library, e.g. versionCatalogs.named("libs").findLibrary("x").get()
+func catalogAPILibrary(dialect Dialect, catalog string, alias string) string {
+	return fmt.Sprintf("%s.findLibrary(%s).get()", catalogAPI(dialect, catalog), dialect.quote(alias, "'"))
+}
+
+// catalogAPIVersion returns the catalog API expression of a version as a string.
+func catalogAPIVersion(dialect Dialect, catalog string, key string) string {
+	return fmt.Sprintf("%s.findVersion(%s).get().requiredVersion", catalogAPI(dialect, catalog), dialect.quote(key, "'"))
+}
+
+// findPrecompiledScripts lists the precompiled script plugins of the builds included by the build in root.
+func findPrecompiledScripts(root string) ([]string, error) {
+	return findIncludedBuildSources(root, precompiledScriptDirectories, isBuildScript)
+}
+
+func librariesForClass(catalog string) string {
+	runes := []rune(catalog)
+	runes[0] = unicode.ToUpper(runes[0])
+	return "org.gradle.accessors.dm.LibrariesFor" + string(runes)
+}
+
+var pluginsBlockExtractor = regexp.MustCompile(`(?m)^\s*plugins\s*\{`)
+
+// declareLibrariesFor adds `val libs = the<LibrariesForLibs>()` for each catalog used by a Kotlin precompiled script,
+// right after its plugins block.
+func declareLibrariesFor(content string, catalogs []string) string {
+	var declarations strings.Builder
+	for _, catalog := range catalogs {
+		if !regexp.MustCompile(`\b`+catalog+`\.`).MatchString(content) ||
+			regexp.MustCompile(`\bval\s+`+catalog+`\s*=`).MatchString(content) {
+			continue
+		}
+		declarations.WriteString(fmt.Sprintf("val %s = the<%s>()%s", catalog, librariesForClass(catalog), LineBreak))
+	}
+	if declarations.Len() == 0 {
+		return content
+	}
+	if location := pluginsBlockExtractor.FindStringIndex(stripComments(content)); location != nil {
+		end := location[1] + len(blockBody(content, location[1]-1)) + 1
+		if lineEnd := strings.IndexByte(content[end:], '\n'); lineEnd >= 0 {
+			end += lineEnd + 1
+		} else {
+			content += LineBreak
+			end = len(content)
+		}
+		return content[:end] + LineBreak + declarations.String() + content[end:]
+	}
+	return declarations.String() + LineBreak + content
+}
+
+// wireLibrariesFor adds the generated accessors to the builds whose Kotlin precompiled script plugins
+// were rewritten in the LibrariesFor style.
+func wireLibrariesFor(root string, rewrittenFiles []string, catalogs []string, changes *ChangeSet) error {
+	builds, err := findIncludedBuilds(root)
+	if err != nil {
+		return err
+	}
+	wired := make(map[string]bool)
+	for _, file := range rewrittenFiles {
+		if !isPrecompiledScriptPlugin(file) || dialectOf(file) != Kotlin {
+			continue
+		}
+		build, ok := owningBuild(builds, file)
+		if !ok || wired[build] {
+			continue
+		}
+		wired[build] = true
+		if err := addLibrariesForDependency(build, catalogs, changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addLibrariesForDependency puts the generated catalog accessors on the classpath of the build in dir,
+// which makes LibrariesForLibs visible to its precompiled script plugins.
+func addLibrariesForDependency(dir string, catalogs []string, changes *ChangeSet) error {
+	path := filepath.Join(dir, "build.gradle.kts")
+	content, err := changes.read(path)
+	if os.IsNotExist(err) {
+		fmt.Printf("NOTICE: %s is missing, add the generated accessors to the classpath of %s by hand.%s", path, dir, LineBreak)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var lines strings.Builder
+	for _, catalog := range catalogs {
+		line := fmt.Sprintf("implementation(files(%s.javaClass.superclass.protectionDomain.codeSource.location))", catalog)
+		if !strings.Contains(content, line) {
+			lines.WriteString(fmt.Sprintf("    %s%s", line, LineBreak))
+		}
+	}
+	if lines.Len() == 0 {
+		return nil
+	}
+	if location := regexp.MustCompile(`(?m)^dependencies\s*\{[ \t]*\r?\n`).FindStringIndex(content); location != nil {
+		content = content[:location[1]] + lines.String() + content[location[1]:]
+	} else {
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += LineBreak
+		}
+		content += "dependencies {" + LineBreak + lines.String() + "}" + LineBreak
+	}
+	changes.write(path, content)
+	return nil
+}
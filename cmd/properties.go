@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Property is an entry of a Java properties file.
+type Property struct {
+	Key   string
+	Value string
+	// start and end delimit the logical line of the entry, continuation lines and line break included.
+	start, end int
+}
+
+// parseProperties reads content in the format of java.util.Properties: # and ! comments,
+// =, : or whitespace separators, backslash escapes and lines continued by a trailing backslash.
+func parseProperties(content string) []Property {
+	properties := make([]Property, 0)
+	for pos := 0; pos < len(content); {
+		start := pos
+		var line strings.Builder
+		for {
+			end := strings.IndexByte(content[pos:], '\n')
+			natural := content[pos:]
+			if end < 0 {
+				pos = len(content)
+			} else {
+				natural = content[pos : pos+end]
+				pos += end + 1
+			}
+			// leading whitespace is insignificant, on continuation lines too
+			natural = strings.TrimLeft(strings.TrimRight(natural, "\r"), " \t\f")
+			if line.Len() == 0 && (natural == "" || natural[0] == '#' || natural[0] == '!') {
+				break
+			}
+			if !continuesLine(natural) {
+				line.WriteString(natural)
+				break
+			}
+			line.WriteString(natural[:len(natural)-1])
+			if pos >= len(content) {
+				break
+			}
+		}
+		if line.Len() == 0 {
+			continue
+		}
+		key, value := splitProperty(line.String())
+		properties = append(properties, Property{Key: key, Value: value, start: start, end: pos})
+	}
+	return properties
+}
+
+// continuesLine tells whether a natural line ends with an odd number of backslashes.
+func continuesLine(line string) bool {
+	backslashes := len(line) - len(strings.TrimRight(line, `\`))
+	return backslashes%2 == 1
+}
+
+func splitProperty(line string) (string, string) {
+	end := 0
+	for end < len(line) {
+		c := line[end]
+		if c == '\\' {
+			end += 2
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			break
+		}
+		end++
+	}
+	end = min(end, len(line))
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	return unescapeProperty(line[:end]), unescapeProperty(rest)
+}
+
+func unescapeProperty(text string) string {
+	if !strings.Contains(text, `\`) {
+		return text
+	}
+	var builder strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 >= len(text) {
+			builder.WriteByte(text[i])
+			continue
+		}
+		i++
+		switch text[i] {
+		case 't':
+			builder.WriteByte('\t')
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case 'f':
+			builder.WriteByte('\f')
+		case 'u':
+			if code, err := strconv.ParseUint(text[i+1:min(i+5, len(text))], 16, 32); err == nil && i+5 <= len(text) {
+				builder.WriteRune(rune(code))
+				i += 4
+			} else {
+				builder.WriteByte('u')
+			}
+		default:
+			builder.WriteByte(text[i])
+		}
+	}
+	return builder.String()
+}
+
+// removeProperty deletes the entries of key from content, continuation lines included.
+func removeProperty(content string, key string) string {
+	properties := parseProperties(content)
+	for i := len(properties) - 1; i >= 0; i-- {
+		if properties[i].Key == key {
+			content = content[:properties[i].start] + content[properties[i].end:]
+		}
+	}
+	return content
+}
+
+// PropertyFiles reads the Gradle properties of a build: gradle.properties in the directory of a project
+// and its parent directories up to the root, then the extra files given with --properties-file.
+type PropertyFiles struct {
+	root   string
+	extra  []string
+	parsed map[string][]Property
+}
+
+func newPropertyFiles(root string, extra []string) *PropertyFiles {
+	return &PropertyFiles{root: root, extra: extra, parsed: make(map[string][]Property)}
+}
+
+// directories lists dir and its parents up to the root, nearest first.
+func (p *PropertyFiles) directories(dir string) []string {
+	directories := make([]string, 0)
+	for ; ; dir = filepath.Dir(dir) {
+		directories = append(directories, dir)
+		relative, err := filepath.Rel(p.root, dir)
+		if err != nil || relative == "." || strings.HasPrefix(relative, "..") || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	return directories
+}
+
+// property returns the value of key in the property file at path.
+func (p *PropertyFiles) property(path string, key string) (string, bool, error) {
+	properties, err := p.read(path)
+	if err != nil {
+		return "", false, err
+	}
+	for _, property := range properties {
+		if property.Key == key {
+			return property.Value, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// read parses the property file at path. A missing file has no properties.
+func (p *PropertyFiles) read(path string) ([]Property, error) {
+	if properties, ok := p.parsed[path]; ok {
+		return properties, nil
+	}
+	bytes, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	p.parsed[path] = parseProperties(string(bytes))
+	return p.parsed[path], nil
+}
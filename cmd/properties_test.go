@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseProperties(t *testing.T) {
+	content := "# comment\n" +
+		"! another comment\n" +
+		"  spaced = 1.0\n" +
+		"colon:2.0\n" +
+		"whitespace 3.0\n" +
+		"continued = 4.\\\n" +
+		"    0\n" +
+		"escaped\\ key = a\\tb\\u0041\\\\\n" +
+		"empty\n" +
+		"crlf=5.0\r\n" +
+		"last=6.0"
+	properties := parseProperties(content)
+	values := make(map[string]string)
+	for _, property := range properties {
+		values[property.Key] = property.Value
+	}
+	assert.Equal(t, map[string]string{
+		"spaced":      "1.0",
+		"colon":       "2.0",
+		"whitespace":  "3.0",
+		"continued":   "4.0",
+		"escaped key": "a\tbA\\",
+		"empty":       "",
+		"crlf":        "5.0",
+		"last":        "6.0",
+	}, values)
+
+	assert.Equal(t, "# comment\n! another comment\n  spaced = 1.0\ncolon:2.0\nwhitespace 3.0\nescaped\\ key = a\\tb\\u0041\\\\\nempty\ncrlf=5.0\r\nlast=6.0",
+		removeProperty(content, "continued"))
+}
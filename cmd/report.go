@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Report collects what a generate run left for the user to review.
+type Report struct {
+	Skipped     []SkippedDeclaration
+	Conflicts   []VersionConflict
+	Differences []CatalogDifference
+	Unresolved  []UnresolvedVariable
+	// SourceSets lists the Kotlin Multiplatform source sets declaring each "group:name".
+	SourceSets map[string][]string
+}
+
+// SkippedDeclaration is a declaration excluded from the migration by a gvc:ignore marker.
+type SkippedDeclaration struct {
+	Path        string
+	Line        int
+	Declaration string
+}
+
+// UnresolvedVariable is a version variable whose value could not be told, so its declarations were left as they are.
+type UnresolvedVariable struct {
+	Path string
+	Line int
+	Name string
+}
+
+func (r *Report) addSourceSet(coordinate string, sourceSet string) {
+	if r.SourceSets == nil {
+		r.SourceSets = make(map[string][]string)
+	}
+	if !slices.Contains(r.SourceSets[coordinate], sourceSet) {
+		r.SourceSets[coordinate] = append(r.SourceSets[coordinate], sourceSet)
+	}
+}
+
+func (r *Report) Print() {
+	if len(r.Skipped) == 0 && len(r.Conflicts) == 0 && len(r.Differences) == 0 && len(r.Unresolved) == 0 && len(r.SourceSets) == 0 {
+		return
+	}
+	fmt.Printf("Summary:%s", LineBreak)
+	if len(r.Skipped) > 0 {
+		fmt.Printf("  Skipped by gvc markers:%s", LineBreak)
+		for _, skipped := range r.Skipped {
+			fmt.Printf("    %s:%d: %s%s", skipped.Path, skipped.Line, skipped.Declaration, LineBreak)
+		}
+	}
+	if len(r.Unresolved) > 0 {
+		fmt.Printf("  Unresolved version variables:%s", LineBreak)
+		for _, unresolved := range r.Unresolved {
+			fmt.Printf("    %s:%d: $%s%s", unresolved.Path, unresolved.Line, unresolved.Name, LineBreak)
+		}
+	}
+	if len(r.Conflicts) > 0 {
+		fmt.Printf("  Version conflicts:%s", LineBreak)
+		for _, conflict := range r.Conflicts {
+			fmt.Printf("    %s%s", conflict, LineBreak)
+		}
+	}
+	if len(r.SourceSets) > 0 {
+		fmt.Printf("  Source sets:%s", LineBreak)
+		for _, coordinate := range slices.Sorted(maps.Keys(r.SourceSets)) {
+			fmt.Printf("    %s: %s%s", coordinate, strings.Join(slices.Sorted(slices.Values(r.SourceSets[coordinate])), ", "), LineBreak)
+		}
+	}
+	if len(r.Differences) > 0 {
+		fmt.Printf("  Differences from the existing catalog:%s", LineBreak)
+		for _, difference := range r.Differences {
+			fmt.Printf("    %s%s", difference, LineBreak)
+		}
+	}
+}
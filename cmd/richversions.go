@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"regexp"
+	"strings"
+)
+
+var versionBlockExtractor = regexp.MustCompile(`(?m)(?:^[ \t]*|[ \t]*;?[ \t]*)\bversion\s*\{`)
+var versionConstraintExtractor = regexp.MustCompile(`\b(strictly|require|prefer|reject)\b\s*\(?\s*((?:["'][^"'\r\n]*["']\s*,?\s*)+)\)?|\b(rejectAll)\s*\(\s*\)`)
+var constraintValueExtractor = regexp.MustCompile(`["']([^"'\r\n]*)["']`)
+
+// isDynamicVersion tells whether version is a range or a dynamic version, e.g. [1.0,2.0), 1.+ or latest.release,
+// which Gradle treats as a required version to be resolved.
+func isDynamicVersion(version string) bool {
+	return strings.HasSuffix(version, "+") || strings.HasPrefix(version, "latest.") || strings.ContainsAny(version[:1], "[]()")
+}
+
+// richVersionOf returns the rich version of a declared version, e.g. strictly for 1.5!!
+// and strictly and prefer for [1.0,2.0)!!1.5, or require for a dynamic version.
+// A plain version has none.
+func richVersionOf(version string) LooseLibrary {
+	if version == "" || version == "FIXME" || strings.HasPrefix(version, "$") {
+		return nil
+	}
+	if strictly, prefer, ok := strings.Cut(version, "!!"); ok {
+		rich := LooseLibrary{"strictly": strictly}
+		if prefer != "" {
+			rich["prefer"] = prefer
+		}
+		return rich
+	}
+	if isDynamicVersion(version) {
+		return LooseLibrary{"require": version}
+	}
+	return nil
+}
+
+// versionBlockAfter finds the version { } block in the closure following a declaration ending at end,
+// e.g. implementation("g:a") { version { strictly("1.0") } }, and returns its bounds and the bounds of the closure.
+func versionBlockAfter(text string, end int) (block [2]int, closure [2]int, ok bool) {
+	open := end
+	for open < len(text) && (text[open] == ' ' || text[open] == '\t') {
+		open++
+	}
+	if open >= len(text) || text[open] != '{' {
+		return block, closure, false
+	}
+	body := blockBody(text, open)
+	closure = [2]int{end, open + len(body) + 2}
+	location := versionBlockExtractor.FindStringIndex(body)
+	if location == nil {
+		return block, closure, false
+	}
+	start := open + 1 + location[0]
+	versionOpen := open + 1 + location[1] - 1
+	block = [2]int{start, versionOpen + len(blockBody(text, versionOpen)) + 2}
+	block[1] += len(statementEndExtractor.FindString(text[block[1]:]))
+	return block, closure, true
+}
+
+// parseVersionBlock collects the constraints of a version { } block.
+func parseVersionBlock(block string) LooseLibrary {
+	rich := make(LooseLibrary)
+	for _, match := range versionConstraintExtractor.FindAllStringSubmatch(stripComments(block), -1) {
+		if match[3] == "rejectAll" {
+			rich["rejectAll"] = true
+			continue
+		}
+		values := make([]string, 0)
+		for _, value := range constraintValueExtractor.FindAllStringSubmatch(match[2], -1) {
+			values = append(values, value[1])
+		}
+		if match[1] == "reject" {
+			rejected, _ := rich["reject"].([]string)
+			rich["reject"] = append(rejected, values...)
+		} else if len(values) > 0 {
+			rich[match[1]] = values[0]
+		}
+	}
+	return rich
+}
+
+// declaredRichVersion returns the rich version of a declaration whose version is given as declared
+// and which ends at end in text, merging the constraints of a following version { } block into it.
+// A version read from a variable is never rich.
+func declaredRichVersion(text string, end int, version string) LooseLibrary {
+	if strings.HasPrefix(version, "$") {
+		return nil
+	}
+	rich := richVersionOf(version)
+	block, _, ok := versionBlockAfter(text, end)
+	if !ok {
+		return rich
+	}
+	constraints := parseVersionBlock(text[block[0]:block[1]])
+	if len(constraints) == 0 {
+		return rich
+	}
+	if rich == nil {
+		rich = make(LooseLibrary)
+		if version != "" && version != "FIXME" {
+			// the version of the notation is a required version
+			rich["require"] = version
+		}
+	}
+	for key, value := range constraints {
+		rich[key] = value
+	}
+	if _, ok := rich["strictly"]; ok {
+		// strictly replaces the version of the notation rather than adding to it
+		delete(rich, "require")
+	}
+	return rich
+}
+
+// representativeVersion returns the single version a rich version stands for when versions are compared.
+func representativeVersion(rich LooseLibrary) string {
+	for _, key := range []string{"prefer", "strictly", "require"} {
+		if version, ok := rich[key].(string); ok {
+			return version
+		}
+	}
+	return "FIXME"
+}
+
+// removeVersionBlock deletes the version { } block following the declaration ending at end,
+// and the closure holding it if nothing else is left in it.
+func removeVersionBlock(text string, end int) string {
+	block, closure, ok := versionBlockAfter(text, end)
+	if !ok {
+		return text
+	}
+	text = text[:block[0]] + text[block[1]:]
+	closure[1] -= block[1] - block[0]
+	open := strings.IndexByte(text[closure[0]:], '{') + closure[0]
+	if strings.TrimSpace(text[open+1:closure[1]-1]) == "" {
+		text = text[:closure[0]] + text[closure[1]:]
+	}
+	return text
+}
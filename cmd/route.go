@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Kinds of routing rules.
+const (
+	RouteByConfiguration = "config"
+	RouteByGroup         = "group"
+	RouteByKind          = "kind"
+)
+
+var routeKinds = []string{RouteByConfiguration, RouteByGroup, RouteByKind}
+
+// RouteRule sends the entries it matches to another catalog, e.g. config:test*=testLibs.
+type RouteRule struct {
+	Kind    string
+	Pattern string
+	Catalog string
+	pattern *regexp.Regexp
+}
+
+func parseRouteRule(value string) (RouteRule, error) {
+	kind, rest, ok := strings.Cut(value, ":")
+	if !ok || !slices.Contains(routeKinds, kind) {
+		return RouteRule{}, fmt.Errorf("invalid route %q, must start with one of %v followed by a colon", value, routeKinds)
+	}
+	pattern, catalog, ok := strings.Cut(rest, "=")
+	if !ok || pattern == "" {
+		return RouteRule{}, fmt.Errorf("invalid route %q, must be like %s:<pattern>=<catalog>", value, kind)
+	}
+	if !catalogNamePattern.MatchString(catalog) {
+		return RouteRule{}, fmt.Errorf("invalid route %q, catalog name %q must be a camelCase identifier like testLibs", value, catalog)
+	}
+	if kind == RouteByKind && pattern != "library" && pattern != "plugin" {
+		return RouteRule{}, fmt.Errorf("invalid route %q, kind must be library or plugin", value)
+	}
+	compiled, err := compileGlob(pattern)
+	if err != nil {
+		return RouteRule{}, err
+	}
+	return RouteRule{Kind: kind, Pattern: pattern, Catalog: catalog, pattern: compiled}, nil
+}
+
+// Router picks the catalog of each entry. The first matching rule wins, and entries no rule matches
+// stay in the default catalog.
+type Router struct {
+	Rules   []RouteRule
+	Default string
+	// origins keeps the entries adopted from other catalogs of the build in place
+	origins CatalogRoutes
+}
+
+func parseRouter(values []string, defaultCatalog string) (Router, error) {
+	router := Router{Default: defaultCatalog, origins: newCatalogRoutes()}
+	for _, value := range values {
+		rule, err := parseRouteRule(value)
+		if err != nil {
+			return Router{}, err
+		}
+		router.Rules = append(router.Rules, rule)
+	}
+	return router, nil
+}
+
+func (r Router) isEmpty() bool {
+	return len(r.Rules) == 0 && len(r.origins.libraries) == 0 && len(r.origins.plugins) == 0 && len(r.origins.versions) == 0
+}
+
+// adopt adds the entries of another catalog of the build named name to catalog,
+// so that they are reused, and remembers to route them back there.
+// Entries whose alias is taken in catalog are left alone in their file.
+func (r Router) adopt(catalog VersionCatalog, name string, other VersionCatalog) {
+	for key, version := range other.Versions {
+		if _, ok := catalog.Versions[key]; !ok {
+			catalog.Versions[key] = version
+			r.origins.versions[key] = name
+		}
+	}
+	for alias, library := range other.Libraries {
+		if _, ok := catalog.Libraries[alias]; !ok {
+			catalog.Libraries[alias] = library
+			r.origins.libraries[alias] = name
+		}
+	}
+	for alias, plugin := range other.Plugins {
+		if _, ok := catalog.Plugins[alias]; !ok {
+			catalog.Plugins[alias] = plugin
+			r.origins.plugins[alias] = name
+		}
+	}
+	for alias, bundle := range other.Bundles {
+		if _, ok := catalog.Bundles[alias]; !ok {
+			catalog.Bundles[alias] = bundle
+			r.origins.bundles[alias] = name
+		}
+	}
+}
+
+// library routes a library by its group and the configurations it is declared in.
+// A configuration rule matches only if every usage matches, so that a library used in
+// both implementation and testImplementation stays with the production ones.
+func (r Router) library(alias string, group string, configurations []string) string {
+	if name, ok := r.origins.libraries[alias]; ok {
+		return name
+	}
+	for _, rule := range r.Rules {
+		switch rule.Kind {
+		case RouteByConfiguration:
+			if len(configurations) > 0 && !slices.ContainsFunc(configurations, func(configuration string) bool {
+				return !rule.pattern.MatchString(configuration)
+			}) {
+				return rule.Catalog
+			}
+		case RouteByGroup:
+			if rule.pattern.MatchString(group) {
+				return rule.Catalog
+			}
+		case RouteByKind:
+			if rule.Pattern == "library" {
+				return rule.Catalog
+			}
+		}
+	}
+	return r.Default
+}
+
+// plugin routes a plugin by its id, which group rules are matched against.
+func (r Router) plugin(alias string, id string) string {
+	if name, ok := r.origins.plugins[alias]; ok {
+		return name
+	}
+	for _, rule := range r.Rules {
+		switch rule.Kind {
+		case RouteByGroup:
+			if rule.pattern.MatchString(id) {
+				return rule.Catalog
+			}
+		case RouteByKind:
+			if rule.Pattern == "plugin" {
+				return rule.Catalog
+			}
+		}
+	}
+	return r.Default
+}
+
+// CatalogRoutes records the catalog each entry was routed to, keyed by alias.
+type CatalogRoutes struct {
+	libraries map[string]string
+	plugins   map[string]string
+	versions  map[string]string
+	bundles   map[string]string
+}
+
+func newCatalogRoutes() CatalogRoutes {
+	return CatalogRoutes{
+		libraries: make(map[string]string),
+		plugins:   make(map[string]string),
+		versions:  make(map[string]string),
+		bundles:   make(map[string]string),
+	}
+}
+
+// splitCatalog distributes the entries of catalog among the catalogs chosen by router.
+// configurationsOf returns the configurations a library alias is declared in.
+// A version goes to every catalog referencing it, and to the default one if nothing does.
+func splitCatalog(catalog VersionCatalog, router Router, configurationsOf func(alias string) []string) (map[string]VersionCatalog, CatalogRoutes) {
+	catalogs := map[string]VersionCatalog{router.Default: initVersionCatalog()}
+	routes := newCatalogRoutes()
+	target := func(name string) VersionCatalog {
+		if _, ok := catalogs[name]; !ok {
+			catalogs[name] = initVersionCatalog()
+		}
+		return catalogs[name]
+	}
+	referencedBy := make(map[string][]string)
+	reference := func(version any, name string) {
+		if ref, ok := versionRefOf(version); ok && !slices.Contains(referencedBy[ref], name) {
+			referencedBy[ref] = append(referencedBy[ref], name)
+		}
+	}
+
+	for _, alias := range slices.Sorted(maps.Keys(catalog.Libraries)) {
+		library := catalog.Libraries[alias]
+		coordinate, _ := libraryCoordinate(library)
+		group, _, _ := strings.Cut(coordinate, ":")
+		name := router.library(alias, group, configurationsOf(alias))
+		target(name).Libraries[alias] = library
+		routes.libraries[alias] = name
+		reference(library["version"], name)
+	}
+	for _, alias := range slices.Sorted(maps.Keys(catalog.Plugins)) {
+		plugin := catalog.Plugins[alias]
+		name := router.plugin(alias, plugin.Id)
+		target(name).Plugins[alias] = plugin
+		routes.plugins[alias] = name
+		reference(plugin.Version, name)
+	}
+	for _, key := range slices.Sorted(maps.Keys(catalog.Versions)) {
+		names := referencedBy[key]
+		if origin, ok := router.origins.versions[key]; ok && !slices.Contains(names, origin) {
+			names = append(names, origin)
+		}
+		if len(names) == 0 {
+			names = []string{router.Default}
+		}
+		for _, name := range names {
+			target(name).Versions[key] = catalog.Versions[key]
+		}
+		routes.versions[key] = names[0]
+		if slices.Contains(names, router.Default) {
+			routes.versions[key] = router.Default
+		}
+	}
+	for _, alias := range slices.Sorted(maps.Keys(catalog.Bundles)) {
+		members := catalog.Bundles[alias]
+		name := router.Default
+		if origin, ok := router.origins.bundles[alias]; ok {
+			target(origin).Bundles[alias] = members
+			routes.bundles[alias] = origin
+			continue
+		}
+		for i, member := range members {
+			if i == 0 {
+				name = routes.libraries[member]
+			} else if routes.libraries[member] != name {
+				fmt.Printf("NOTICE: bundle %s refers to libraries in several catalogs, keeping it in %s.%s", alias, router.Default, LineBreak)
+				name = router.Default
+				break
+			}
+		}
+		if name == "" {
+			name = router.Default
+		}
+		target(name).Bundles[alias] = members
+		routes.bundles[alias] = name
+	}
+	return catalogs, routes
+}
+
+// mergeSplitCatalog adds the routed entries to a catalog that already exists.
+func mergeSplitCatalog(existing VersionCatalog, routed VersionCatalog) VersionCatalog {
+	maps.Copy(existing.Versions, routed.Versions)
+	maps.Copy(existing.Libraries, routed.Libraries)
+	maps.Copy(existing.Plugins, routed.Plugins)
+	maps.Copy(existing.Bundles, routed.Bundles)
+	return existing
+}
+
+// accessorTarget finds the entry an accessor chain following the catalog name refers to,
+// e.g. "versions.kotlin.get" or "androidx.core.ktx". The longest matching alias wins,
+// so that libs.foo.bar is not taken for libs.foo. It returns the alias and the catalog it was routed to.
+func (r CatalogRoutes) accessorTarget(chain string) (string, string, bool) {
+	routes := r.libraries
+	for prefix, section := range map[string]map[string]string{"versions.": r.versions, "plugins.": r.plugins, "bundles.": r.bundles} {
+		if strings.HasPrefix(chain, prefix) {
+			chain = strings.TrimPrefix(chain, prefix)
+			routes = section
+			break
+		}
+	}
+	longest := ""
+	target := ""
+	name := ""
+	for alias, catalog := range routes {
+		accessor := accessorSeparators.Replace(alias)
+		if (chain == accessor || strings.HasPrefix(chain, accessor+".")) && len(accessor) > len(longest) {
+			longest = accessor
+			target = alias
+			name = catalog
+		}
+	}
+	return target, name, longest != ""
+}
+
+func compileAccessorExtractor(catalog string) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`\b%s\.(?P<chain>\w+(?:\.\w+)*)`, regexp.QuoteMeta(catalog)))
+}
+
+func compileAccessorUsageExtractor(catalog string) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(
+		`\b(?P<config>\w+)\s*\(?\s*(?:(?:platform|enforcedPlatform|testFixtures|variantOf)\s*\(\s*)?%s\.(?P<chain>\w+(?:\.\w+)*)`,
+		regexp.QuoteMeta(catalog)))
+}
+
+// findAccessorConfigurations lists the configurations each library alias of catalog is used in by build files.
+func findAccessorConfigurations(catalog string, aliases []string, buildFilePaths []string) (map[string][]string, error) {
+	routes := CatalogRoutes{libraries: make(map[string]string)}
+	for _, alias := range aliases {
+		routes.libraries[alias] = catalog
+	}
+	extractor := compileAccessorUsageExtractor(catalog)
+	configurations := make(map[string][]string)
+	for _, path := range buildFilePaths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range extractor.FindAllStringSubmatch(activeText(splitIgnoredSegments(string(content))), -1) {
+			alias, _, ok := routes.accessorTarget(submatch(extractor, match, "chain"))
+			config := submatch(extractor, match, "config")
+			if ok && !slices.Contains(configurations[alias], config) {
+				configurations[alias] = append(configurations[alias], config)
+			}
+		}
+	}
+	return configurations, nil
+}
+
+// rewriteAccessors points the accessors of the catalog named from at the catalogs the entries were routed to.
+func rewriteAccessors(content string, from string, routes CatalogRoutes) string {
+	extractor := compileAccessorExtractor(from)
+	return extractor.ReplaceAllStringFunc(content, func(s string) string {
+		chain := submatch(extractor, extractor.FindStringSubmatch(s), "chain")
+		_, name, ok := routes.accessorTarget(chain)
+		if !ok {
+			return s
+		}
+		return name + "." + chain
+	})
+}
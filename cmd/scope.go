@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Scope limits the build files that are rewritten.
+// Versions are still cataloged from every discovered file so that the shared catalog stays whole.
+type Scope struct {
+	// Modules are Gradle project path patterns like ":app" or ":core:*".
+	Modules []string
+	// Changed holds the absolute paths changed since the --since ref, or is nil if no ref is given.
+	Changed map[string]bool
+}
+
+func (s Scope) isEmpty() bool {
+	return len(s.Modules) == 0 && s.Changed == nil
+}
+
+// filter returns the files within the scope.
+func (s Scope) filter(root string, files []string) ([]string, error) {
+	if s.isEmpty() {
+		return files, nil
+	}
+	settings, err := readSettings(root)
+	if err != nil {
+		return nil, err
+	}
+	scoped := make([]string, 0, len(files))
+	for _, file := range files {
+		if s.Changed != nil {
+			absolute, err := filepath.Abs(file)
+			if err != nil {
+				return nil, err
+			}
+			if !s.Changed[absolute] {
+				continue
+			}
+		}
+		if len(s.Modules) > 0 {
+			// module patterns are matched as slash separated globs, e.g. :core:* -> core/*
+			projectPath := strings.ReplaceAll(strings.TrimPrefix(projectPathOf(root, settings, file), ":"), ":", "/")
+			patterns := make([]string, len(s.Modules))
+			for i, module := range s.Modules {
+				patterns[i] = strings.ReplaceAll(strings.TrimPrefix(module, ":"), ":", "/")
+			}
+			ok, err := matchesAnyGlob(patterns, projectPath)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		scoped = append(scoped, file)
+	}
+	return scoped, nil
+}
+
+// projectPathOf returns the Gradle project path of the module a build file belongs to.
+// Modules not declared in settings are named after their directory relative to root.
+func projectPathOf(root string, settings *GradleSettings, file string) string {
+	dir := filepath.Dir(file)
+	if settings != nil {
+		for projectPath, projectDir := range settings.Projects {
+			if filepath.Clean(projectDir) == filepath.Clean(dir) {
+				return projectPath
+			}
+		}
+	}
+	relative, err := filepath.Rel(root, dir)
+	if err != nil || relative == "." {
+		return ":"
+	}
+	return ":" + strings.ReplaceAll(filepath.ToSlash(relative), "/", ":")
+}
+
+// scopeVariables drops the version variables defined in build files out of the scope,
+// so that those files are left untouched.
+func scopeVariables(variables []VersionVariable, allFiles []string, scopedFiles []string) []VersionVariable {
+	inScope := make(map[string]bool)
+	for _, file := range scopedFiles {
+		inScope[file] = true
+	}
+	isBuildFile := make(map[string]bool)
+	for _, file := range allFiles {
+		isBuildFile[file] = true
+	}
+	scoped := make([]VersionVariable, 0, len(variables))
+	for _, variable := range variables {
+		if isBuildFile[variable.Path] && !inScope[variable.Path] {
+			continue
+		}
+		scoped = append(scoped, variable)
+	}
+	return scoped
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// GradleSettings is what is statically known from a settings.gradle(.kts).
+type GradleSettings struct {
+	// Path is the settings file itself.
+	Path string
+	// Projects maps a project path like ":core:data" to its directory.
+	Projects map[string]string
+	// IncludedBuilds are the directories of builds included via includeBuild(...).
+	IncludedBuilds []string
+	// Catalogs maps the names of catalogs declared in versionCatalogs { ... } to their TOML files.
+	Catalogs map[string]string
+	// DefaultLibrariesExtensionName is the name Gradle gives the catalog in gradle/libs.versions.toml.
+	DefaultLibrariesExtensionName string
+}
+
+var (
+	includeExtractor      = regexp.MustCompile(`\binclude(Flat)?\b\s*\(?((?:\s*["'][^"'\r\n]+["']\s*,?)+)\)?`)
+	includeBuildExtractor = regexp.MustCompile(`\bincludeBuild\b\s*\(?\s*["']([^"'\r\n]+)["']`)
+	projectDirExtractor   = regexp.MustCompile(`\bproject\(\s*["']([^"'\r\n]+)["']\s*\)\.projectDir\s*=\s*(?:file\(\s*|(?:new\s+)?File\(\s*(?:settingsDir|rootDir|rootProject\.projectDir)\s*,\s*)["']([^"'\r\n]+)["']`)
+	stringLiteralPattern  = regexp.MustCompile(`["']([^"'\r\n]+)["']`)
+	settingsDirVariables  = regexp.MustCompile(`^\$\{?(?:settingsDir|rootDir)}?/`)
+
+	versionCatalogsExtractor = regexp.MustCompile(`\bversionCatalogs\s*\{`)
+	// create("deps") { from(files("...")) } in Kotlin, deps { from files('...') } in Groovy
+	catalogDeclarationExtractor            = regexp.MustCompile(`(?:\bcreate\(\s*["'](?P<created>\w+)["']\s*\)|\b(?P<named>\w+))\s*\{[^{}]*?\bfrom\s*\(?\s*files\(\s*["'](?P<path>[^"'\r\n]+)["']`)
+	defaultLibrariesExtensionNameExtractor = regexp.MustCompile(`\bdefaultLibrariesExtensionName(?:\.set\(\s*|\s*=\s*)["'](\w+)["']`)
+)
+
+// readSettings parses the settings file of the build in dir. It returns nil if there is none.
+func readSettings(dir string) (*GradleSettings, error) {
+	path := ""
+	for _, d := range []Dialect{Kotlin, Groovy} {
+		candidate := filepath.Join(dir, d.settingsFileName())
+		if _, err := os.Stat(candidate); err == nil {
+			path = candidate
+			break
+		}
+	}
+	if path == "" {
+		return nil, nil
+	}
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content := stripComments(string(bytes))
+
+	settings := &GradleSettings{
+		Path:                          path,
+		Projects:                      make(map[string]string),
+		Catalogs:                      make(map[string]string),
+		DefaultLibrariesExtensionName: defaultCatalogName,
+	}
+	for _, match := range includeExtractor.FindAllStringSubmatch(content, -1) {
+		flat := match[1] != ""
+		for _, literal := range stringLiteralPattern.FindAllStringSubmatch(match[2], -1) {
+			projectPath := literal[1]
+			if !strings.HasPrefix(projectPath, ":") {
+				projectPath = ":" + projectPath
+			}
+			if flat {
+				// a sibling of dir, which filepath.Dir would miss for a relative dir such as "."
+				settings.Projects[projectPath] = filepath.Join(dir, "..", projectPath[1:])
+				continue
+			}
+			// include(":a:b") also creates the project ":a"
+			segments := strings.Split(projectPath[1:], ":")
+			for i := range segments {
+				parent := ":" + strings.Join(segments[:i+1], ":")
+				if _, ok := settings.Projects[parent]; !ok {
+					settings.Projects[parent] = filepath.Join(append([]string{dir}, segments[:i+1]...)...)
+				}
+			}
+		}
+	}
+	for _, match := range projectDirExtractor.FindAllStringSubmatch(content, -1) {
+		projectPath := match[1]
+		if !strings.HasPrefix(projectPath, ":") {
+			projectPath = ":" + projectPath
+		}
+		settings.Projects[projectPath] = resolveSettingsPath(dir, match[2])
+	}
+	for _, match := range includeBuildExtractor.FindAllStringSubmatch(content, -1) {
+		settings.IncludedBuilds = append(settings.IncludedBuilds, resolveSettingsPath(dir, match[1]))
+	}
+	for _, location := range versionCatalogsExtractor.FindAllStringIndex(content, -1) {
+		body := blockBody(content, location[1]-1)
+		for _, match := range catalogDeclarationExtractor.FindAllStringSubmatch(body, -1) {
+			name := submatch(catalogDeclarationExtractor, match, "created")
+			if name == "" {
+				name = submatch(catalogDeclarationExtractor, match, "named")
+			}
+			settings.Catalogs[name] = resolveSettingsPath(dir, submatch(catalogDeclarationExtractor, match, "path"))
+		}
+	}
+	if match := defaultLibrariesExtensionNameExtractor.FindStringSubmatch(content); match != nil {
+		settings.DefaultLibrariesExtensionName = match[1]
+	}
+	return settings, nil
+}
+
+// blockBody returns the text between the brace at open and the brace closing it.
+func blockBody(content string, open int) string {
+	depth := 0
+	for i := open; i < len(content); i++ {
+		switch content[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return content[open+1 : i]
+			}
+		}
+	}
+	return content[open+1:]
+}
+
+func resolveSettingsPath(dir string, path string) string {
+	path = settingsDirVariables.ReplaceAllString(path, "")
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, filepath.FromSlash(path))
+}
+
+// stripComments blanks out // and /* */ comments outside string literals.
+// Line breaks are kept so that offsets and line numbers stay valid.
+func stripComments(content string) string {
+	out := []byte(content)
+	var quote byte
+	for i := 0; i < len(out); i++ {
+		c := out[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote || c == '\n' {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				end = len(out) - i - 2
+			}
+			for j := i; j < i+2+end+2 && j < len(out); j++ {
+				if out[j] != '\n' {
+					out[j] = ' '
+				}
+			}
+			i += end + 3
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// SourceSetBlock is the block configuring a source set of a Kotlin Multiplatform project,
+// e.g. commonMain.dependencies { } or val jvmTest by getting { } in sourceSets { }.
+type SourceSetBlock struct {
+	Name string
+	body [2]int
+}
+
+// the configurations of the source sets of a Kotlin Multiplatform project, e.g. jvmTestImplementation
+const sourceSetConfigurationPattern = `[a-z]\w*(?:Main|Test)(?:Implementation|Api|CompileOnly|RuntimeOnly)`
+
+var kotlinExtensionExtractor = regexp.MustCompile(`\bkotlin\s*\{`)
+var sourceSetsExtractor = regexp.MustCompile(`\bsourceSets\s*\{`)
+
+// sourceSets.commonMain.dependencies { } of Kotlin 2
+var flatSourceSetExtractor = regexp.MustCompile(`\bsourceSets\.(\w+)(?:\.dependencies)?\s*\{`)
+var sourceSetBlockExtractor = regexp.MustCompile(`(?:\bval\s+(?P<val>\w+)\s+by\s+(?:getting|creating)|\b(?:getByName|named|create|maybeCreate|register)\(\s*["'](?P<named>\w+)["']\s*\)|\b(?P<member>\w+)(?:\.dependencies)?)\s*\{`)
+var sourceSetConfigurationExtractor = regexp.MustCompile(`^(\w+?(?:Main|Test))(?:Implementation|Api|CompileOnly|RuntimeOnly)$`)
+
+// blocks in sourceSets { } configuring several source sets or something else than one
+var notSourceSets = []string{"all", "configureEach", "matching", "withType", "dependencies", "languageSettings"}
+
+// findSourceSetBlocks collects the source set blocks of kotlin { sourceSets { } } in text, skipping comments,
+// and tells whether kotlin { } configures source sets at all, which is what a Kotlin Multiplatform project does,
+// e.g. with the plugin applied by a convention plugin.
+func findSourceSetBlocks(text string) ([]SourceSetBlock, bool) {
+	stripped := stripComments(text)
+	blocks := make([]SourceSetBlock, 0)
+	multiplatform := false
+	for end := 0; end < len(stripped); {
+		location := kotlinExtensionExtractor.FindStringIndex(stripped[end:])
+		if location == nil {
+			break
+		}
+		open := end + location[1] - 1
+		body := blockBody(stripped, open)
+		found, ok := findSourceSetBlocksIn(body, open+1)
+		blocks = append(blocks, found...)
+		multiplatform = multiplatform || ok
+		end = open + len(body) + 1
+	}
+	return blocks, multiplatform
+}
+
+// findSourceSetBlocksIn collects the source set blocks of the body of kotlin { } starting at offset.
+func findSourceSetBlocksIn(text string, offset int) ([]SourceSetBlock, bool) {
+	blocks := make([]SourceSetBlock, 0)
+	flat := flatSourceSetExtractor.FindAllStringSubmatchIndex(text, -1)
+	for _, match := range flat {
+		open := match[1] - 1
+		body := blockBody(text, open)
+		blocks = append(blocks, SourceSetBlock{Name: text[match[2]:match[3]], body: [2]int{offset + open + 1, offset + open + 1 + len(body)}})
+	}
+	nested := sourceSetsExtractor.FindAllStringIndex(text, -1)
+	for _, location := range nested {
+		open := location[1] - 1
+		body := blockBody(text, open)
+		// only the blocks directly in sourceSets { }, skipping what they hold
+		for pos := 0; pos < len(body); {
+			match := sourceSetBlockExtractor.FindStringSubmatchIndex(body[pos:])
+			if match == nil {
+				break
+			}
+			groups := stringsOf(body[pos:], match)
+			name := submatch(sourceSetBlockExtractor, groups, "val") + submatch(sourceSetBlockExtractor, groups, "named") + submatch(sourceSetBlockExtractor, groups, "member")
+			brace := pos + match[1] - 1
+			block := blockBody(body, brace)
+			if !slices.Contains(notSourceSets, name) {
+				start := offset + open + 2 + brace
+				blocks = append(blocks, SourceSetBlock{Name: name, body: [2]int{start, start + len(block)}})
+			}
+			pos = brace + len(block) + 2
+		}
+	}
+	return blocks, len(flat)+len(nested) > 0
+}
+
+// sourceSetOf returns the source set a declaration at offset with the given configuration belongs to, if any,
+// and the configuration Gradle adds it to, e.g. commonTest and commonTestImplementation for implementation in commonTest { }.
+func sourceSetOf(blocks []SourceSetBlock, offset int, configuration string) (string, string) {
+	if match := sourceSetConfigurationExtractor.FindStringSubmatch(configuration); match != nil {
+		return match[1], configuration
+	}
+	for _, block := range blocks {
+		if block.body[0] <= offset && offset < block.body[1] {
+			switch configuration {
+			case "implementation", "api", "compileOnly", "runtimeOnly":
+				return block.Name, block.Name + strings.ToUpper(configuration[:1]) + configuration[1:]
+			}
+			return block.Name, configuration
+		}
+	}
+	return "", configuration
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"maps"
+	"os"
+	"slices"
+)
+
+var splitCommand = &cobra.Command{
+	Use:   "split [PATH]",
+	Short: "Split an existing catalog into several catalogs by rules",
+	Long: `
+Moves the entries of an existing catalog (PATH/gradle/libs.versions.toml by default) into other catalogs
+chosen by --route rules, points the accessors in build files at the new catalogs,
+and registers the new catalogs in the settings files.
+If no PATH is provided, the current working directory is used.
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 1 {
+			return errors.New("requires at most one arg")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		gradleProjectRootPath, err := getWorkingDirectory(args)
+		if err != nil {
+			return err
+		}
+
+		layout, err := readCatalogLayout(gradleProjectRootPath)
+		if err != nil {
+			return fmt.Errorf("failed to read the settings file: %w", err)
+		}
+		catalogName, catalogPath, err := catalogFlags(cmd, layout)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(catalogPath); err != nil {
+			return fmt.Errorf("no catalog to split: %w", err)
+		}
+		routeRules, err := cmd.Flags().GetStringSlice("route")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		router, err := parseRouter(routeRules, catalogName)
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		if router.isEmpty() {
+			return errors.New("error option: at least one --route is required")
+		}
+		maxDepth, err := cmd.Flags().GetInt8("max-depth")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+		allowDirty, err := cmd.Flags().GetBool("allow-dirty")
+		if err != nil {
+			return fmt.Errorf("error option: %w", err)
+		}
+
+		workTree, err := findGitWorkTree(gradleProjectRootPath)
+		if err != nil {
+			return fmt.Errorf("failed to detect a git work tree: %w", err)
+		}
+		workTree, err = inspectableWorkTree(workTree, allowDirty)
+		if err != nil {
+			return err
+		}
+		foundFiles, err := discoverBuildFiles(gradleProjectRootPath, int(maxDepth), PathFilter{}, workTree)
+		if err != nil {
+			return fmt.Errorf("error during listing up build.gradle files: %w", err)
+		}
+
+		catalog, err := ReadCatalog(catalogPath)
+		if err != nil {
+			return fmt.Errorf("failed to read the existing %s: %w", catalogPath, err)
+		}
+		configurations, err := findAccessorConfigurations(catalogName, slices.Collect(maps.Keys(catalog.Libraries)), foundFiles)
+		if err != nil {
+			return fmt.Errorf("failed to read build files: %w", err)
+		}
+		catalogs, routes := splitCatalog(*catalog, router, func(alias string) []string {
+			return configurations[alias]
+		})
+		catalogPaths := map[string]string{catalogName: catalogPath}
+		for name := range catalogs {
+			if name != catalogName {
+				catalogPaths[name] = layout.path(name)
+			}
+		}
+
+		changes := newChangeSet()
+		rewrittenFiles := make([]string, 0)
+		for _, path := range foundFiles {
+			content, err := changes.read(path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			updated := rewriteActiveSegments(content, func(text string) string {
+				return rewriteAccessors(text, catalogName, routes)
+			})
+			if updated != content {
+				rewrittenFiles = append(rewrittenFiles, path)
+				changes.write(path, updated)
+			}
+		}
+
+		includedBuilds, err := buildsToWire(gradleProjectRootPath, rewrittenFiles)
+		if err != nil {
+			return fmt.Errorf("failed to read the settings of included builds: %w", err)
+		}
+		err = registerCatalogs(layout, catalogPaths, includedBuilds, changes)
+		if err != nil {
+			return err
+		}
+		err = writeCatalogs(catalogs, catalogPaths, catalogPath, changes)
+		if err != nil {
+			return err
+		}
+
+		if workTree != nil && !allowDirty {
+			err = workTree.ensureClean(changes.paths())
+			if err != nil {
+				return err
+			}
+		}
+		err = changes.apply()
+		if err != nil {
+			return fmt.Errorf("failed to write files: %w", err)
+		}
+		for _, name := range slices.Sorted(maps.Keys(catalogPaths)) {
+			fmt.Printf("Generated: %s%s", catalogPaths[name], LineBreak)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(splitCommand)
+	splitCommand.Flags().StringSlice("route", nil, "Rules sending entries to other catalogs, the first match wins: config:<glob>=<catalog>, group:<glob>=<catalog> or kind:library|plugin=<catalog>")
+	splitCommand.Flags().String("catalog-name", defaultCatalogName, "Name of the catalog to split")
+	splitCommand.Flags().String("catalog-path", "", "Path of the catalog file relative to PATH. Defaults to gradle/<catalog-name>.versions.toml")
+	splitCommand.Flags().Int8("max-depth", 3, "Maximum depth of searching gradle files when settings.gradle(.kts) includes no modules. Project root is 0. Defaults to 3.")
+	splitCommand.Flags().Bool("allow-dirty", false, "Rewrite files even if they have uncommitted changes in git")
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PinnedVersion is a version a resolution rule forces onto the modules it selects,
+// e.g. useVersion("1.9.10") under if (requested.group == "org.jetbrains.kotlin") in eachDependency { }.
+type PinnedVersion struct {
+	// Name is derived from the modules selected, e.g. org-jetbrains-kotlin
+	Name  string
+	Value string
+}
+
+// force("g:a:1.0", "g:b:2.0") with several modules; a single one is a plain declaration of the force configuration
+var forceListExtractor = regexp.MustCompile(`\bforce(?P<open>\s*\(\s*|\s+)(?P<arguments>["'][^"'\r\n]+["'](?:\s*,\s*["'][^"'\r\n]+["'])+)`)
+var forceArgumentExtractor = regexp.MustCompile(`["']([^:"'\r\n]+):([^:"'\r\n]+):([^:"'$\r\n]+)["']`)
+var eachDependencyExtractor = regexp.MustCompile(`\beachDependency\s*\{`)
+var useVersionExtractor = regexp.MustCompile(`(?P<head>\buseVersion\s*\(?\s*)["'](?P<version>[^"'$\r\n]+)["']`)
+var requestedGroupExtractor = regexp.MustCompile(`\brequested\.group\s*==\s*["']([^"'\r\n]+)["']`)
+var requestedNameExtractor = regexp.MustCompile(`\brequested\.name\s*==\s*["']([^"'\r\n]+)["']`)
+var substitutionTargetExtractor = regexp.MustCompile(`(?P<head>\b(?:using|with)\s*\(?\s*module\s*\(?\s*)["'](?P<group>[^:"'\r\n]+):(?P<name>[^:"'\r\n]+):(?P<version>[^:"'$\r\n]+)["']`)
+
+// findForcedLibraries collects the modules of force lists, e.g. force("g:a:1.0", "g:b:2.0")
+func findForcedLibraries(text string) []StrictLibrary {
+	libraries := make([]StrictLibrary, 0)
+	for _, match := range forceListExtractor.FindAllStringSubmatch(text, -1) {
+		arguments := match[forceListExtractor.SubexpIndex("arguments")]
+		for _, argument := range forceArgumentExtractor.FindAllStringSubmatch(arguments, -1) {
+			libraries = append(libraries, StrictLibrary{Group: argument[1], Name: argument[2], Version: argument[3], Configuration: "force"})
+		}
+	}
+	return libraries
+}
+
+// findPinnedVersions collects the versions of useVersion in eachDependency { } blocks whose modules can be told
+// from a preceding requested.group == "..." condition, and the offsets of their string literals in text.
+func findPinnedVersions(text string) ([]PinnedVersion, [][2]int) {
+	pinned := make([]PinnedVersion, 0)
+	locations := make([][2]int, 0)
+	for _, location := range eachDependencyExtractor.FindAllStringIndex(text, -1) {
+		open := location[1] - 1
+		body := blockBody(text, open)
+		for _, use := range useVersionExtractor.FindAllStringSubmatchIndex(body, -1) {
+			preceding := body[:use[0]]
+			groups := requestedGroupExtractor.FindAllStringSubmatch(preceding, -1)
+			if len(groups) == 0 {
+				continue
+			}
+			name := groups[len(groups)-1][1]
+			if names := requestedNameExtractor.FindAllStringSubmatch(preceding, -1); len(names) > 0 {
+				name += "." + names[len(names)-1][1]
+			}
+			version := 2 * useVersionExtractor.SubexpIndex("version")
+			pinned = append(pinned, PinnedVersion{Name: safeKey(name), Value: body[use[version]:use[version+1]]})
+			// the literal with its quotes
+			locations = append(locations, [2]int{open + 1 + use[version] - 1, open + 1 + use[version+1] + 1})
+		}
+	}
+	return pinned, locations
+}
+
+// findSubstitutionTargets collects the modules substituted in, e.g. substitute(module("g:a")).using(module("g:b:2.0"))
+func findSubstitutionTargets(text string) []StrictLibrary {
+	libraries := make([]StrictLibrary, 0)
+	for _, match := range substitutionTargetExtractor.FindAllStringSubmatch(text, -1) {
+		libraries = append(libraries, StrictLibrary{
+			Group:   submatch(substitutionTargetExtractor, match, "group"),
+			Name:    submatch(substitutionTargetExtractor, match, "name"),
+			Version: submatch(substitutionTargetExtractor, match, "version"),
+		})
+	}
+	return libraries
+}
+
+// rewriteResolutionRules points force lists, useVersion and substitution targets to the catalog.
+// Substitutions only take string notations, so the version is interpolated there, e.g. module("g:b:${libs.versions.g.b.get()}")
+func rewriteResolutionRules(dialect Dialect, content string, path string, aliases Aliases, style PrecompiledStyle) string {
+	versionOf := func(name string, value string) (string, bool) {
+		key, ok := aliases.versionKeys[[2]string{name, value}]
+		if !ok {
+			return "", false
+		}
+		if usesCatalogAPI(path, style) {
+			return catalogAPIVersion(dialect, aliases.versionCatalog(key), key), true
+		}
+		return versionAccessor(aliases.versionCatalog(key), key), true
+	}
+
+	content = forceListExtractor.ReplaceAllStringFunc(content, func(s string) string {
+		match := forceListExtractor.FindStringSubmatch(s)
+		arguments := forceArgumentExtractor.ReplaceAllStringFunc(match[forceListExtractor.SubexpIndex("arguments")], func(argument string) string {
+			parts := forceArgumentExtractor.FindStringSubmatch(argument)
+			lib := StrictLibrary{Group: parts[1], Name: parts[2], Version: parts[3]}
+			alias := aliases.library(path, lib)
+			if usesCatalogAPI(path, style) {
+				return catalogAPILibrary(dialect, aliases.libraryCatalog(alias), alias)
+			}
+			return libraryAccessor(aliases.libraryCatalog(alias), alias)
+		})
+		return "force" + match[forceListExtractor.SubexpIndex("open")] + arguments
+	})
+
+	pinned, locations := findPinnedVersions(content)
+	for i := len(pinned) - 1; i >= 0; i-- {
+		if accessor, ok := versionOf(pinned[i].Name, pinned[i].Value); ok {
+			content = content[:locations[i][0]] + accessor + content[locations[i][1]:]
+		}
+	}
+
+	return substitutionTargetExtractor.ReplaceAllStringFunc(content, func(s string) string {
+		match := substitutionTargetExtractor.FindStringSubmatch(s)
+		lib := StrictLibrary{
+			Group:   submatch(substitutionTargetExtractor, match, "group"),
+			Name:    submatch(substitutionTargetExtractor, match, "name"),
+			Version: submatch(substitutionTargetExtractor, match, "version"),
+		}
+		accessor, ok := versionOf(catalogSafeKey(lib), lib.Version)
+		if !ok {
+			return s
+		}
+		return fmt.Sprintf(`%s"%s:%s:${%s}"`, submatch(substitutionTargetExtractor, match, "head"), lib.Group, lib.Name, strings.TrimSpace(accessor))
+	})
+}
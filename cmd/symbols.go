@@ -0,0 +1,320 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// VersionSymbol is a variable defined in a build script that a version may refer to.
+type VersionSymbol struct {
+	Name string
+	// Value is the assigned string literal, which interpolates other variables if Template is set, e.g. "$major.$minor"
+	Value    string
+	Template bool
+	// Reads is the Gradle property the variable is read from instead of a literal, e.g. val x: String by project
+	Reads string
+	// FromRoot tells that the property is read from, or defined on, the root project, e.g. rootProject.extra["x"]
+	FromRoot bool
+	// Extra tells that the variable is an extra property of the project, visible to its subprojects too,
+	// rather than a variable local to the script.
+	Extra bool
+	Path  string
+	Line  int
+}
+
+// Resolution is the value a version variable resolves to and the definition supplying it.
+type Resolution struct {
+	Value  string
+	Source VersionVariable
+}
+
+const symbolOwnerPattern = `(?:(?P<owner>project|rootProject)\.)?`
+const symbolTypePattern = `(?:\s*:\s*String\??)?`
+const symbolLiteralPattern = `(?:"(?P<template>[^"\r\n]*)"|'(?P<literal>[^'\r\n]*)')`
+const symbolEndPattern = `[ \t]*;?[ \t]*$`
+
+var localSymbolExtractor = regexp.MustCompile(`(?m)^[ \t]*(?:(?:private|internal|const)\s+)*(?:val|var|def|String)\s+(?P<name>\w+)` + symbolTypePattern + `\s*=\s*` + symbolLiteralPattern + symbolEndPattern)
+var extraSymbolExtractors = []*regexp.Regexp{
+	// val x by extra("1.0")
+	regexp.MustCompile(`(?m)^[ \t]*(?:val|var)\s+(?P<name>\w+)` + symbolTypePattern + `\s+by\s+` + symbolOwnerPattern + `extra\(\s*` + symbolLiteralPattern + `\s*\)` + symbolEndPattern),
+	// ext.x = "1.0"
+	regexp.MustCompile(`(?m)^[ \t]*` + symbolOwnerPattern + `ext\.(?P<name>\w+)\s*=\s*` + symbolLiteralPattern + symbolEndPattern),
+	// extra["x"] = "1.0"
+	regexp.MustCompile(`(?m)^[ \t]*` + symbolOwnerPattern + `extra\[\s*"(?P<name>\w+)"\s*]\s*=\s*` + symbolLiteralPattern + symbolEndPattern),
+	// extra.set("x", "1.0")
+	regexp.MustCompile(`(?m)^[ \t]*` + symbolOwnerPattern + `ext(?:ra)?\.set\(\s*["'](?P<name>\w+)["']\s*,\s*` + symbolLiteralPattern + `\s*\)` + symbolEndPattern),
+}
+var extSymbolBlockExtractor = regexp.MustCompile(`(?m)^[ \t]*` + symbolOwnerPattern + `ext\s*\{`)
+var extBlockSymbolExtractor = regexp.MustCompile(`(?m)^[ \t]*(?P<name>\w+)\s*=\s*` + symbolLiteralPattern + symbolEndPattern)
+var readerSymbolExtractors = []*regexp.Regexp{
+	// val x: String by project
+	regexp.MustCompile(`(?m)^[ \t]*(?:val|var)\s+(?P<name>\w+)` + symbolTypePattern + `\s+by\s+(?P<owner>project|rootProject)\b`),
+	// val x = project.property("y"), rootProject.extra["y"], rootProject.ext.y, ...
+	regexp.MustCompile(`(?m)^[ \t]*(?:val|var|def|String)\s+(?P<name>\w+)` + symbolTypePattern + `\s*=\s*` + symbolOwnerPattern +
+		`(?:(?:property|findProperty|providers\.gradleProperty)\(\s*["'](?P<reads>\w+)["']\s*\)` +
+		`|extra\[\s*"(?P<reads>\w+)"\s*]|extra\.get\(\s*"(?P<reads>\w+)"\s*\)` +
+		`|ext\.(?P<reads>\w+)|ext\[\s*["'](?P<reads>\w+)["']\s*])`),
+}
+var templateReferenceExtractor = regexp.MustCompile(`\$(?:\{([^}]*)}|(\w+))`)
+
+// qualified variable names tolerated in versions, e.g. ${rootProject.ext.okhttp}, with the dots turned into underscores
+var variableQualifiers = []struct {
+	prefix   string
+	fromRoot bool
+}{
+	{"rootProject.ext.", true},
+	{"rootProject.extra.", true},
+	{"rootProject.", true},
+	{"project.ext.", false},
+	{"project.", false},
+	{"ext.", false},
+}
+
+const maxSymbolDepth = 16
+
+// SymbolTable resolves version variables the way Gradle sees them from a build script:
+// its local variables, then the extra properties and gradle.properties of its project and of the parent projects,
+// then the extra property files.
+type SymbolTable struct {
+	properties *PropertyFiles
+	// locals are keyed by script, extras by the directory of the project they belong to
+	locals map[string][]VersionSymbol
+	extras map[string][]VersionSymbol
+	// projects maps the scripts applied with apply from to the directory of the project applying them
+	projects map[string]string
+	// objects are the constants of the dependency objects of buildSrc, which every script sees
+	objects []VersionSymbol
+}
+
+func newSymbolTable(buildFilePaths []string, properties *PropertyFiles) (*SymbolTable, error) {
+	table := &SymbolTable{
+		properties: properties,
+		locals:     make(map[string][]VersionSymbol),
+		extras:     make(map[string][]VersionSymbol),
+		projects:   make(map[string]string),
+	}
+	contents := make(map[string]string)
+	for _, path := range buildFilePaths {
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		contents[path] = string(bytes)
+	}
+
+	// scripts may apply scripts applying other scripts, so repeat until every owner is known
+	for changed := true; changed; {
+		changed = false
+		for _, path := range buildFilePaths {
+			for _, match := range applyFromExtractor.FindAllStringSubmatch(stripComments(contents[path]), -1) {
+				applied, ok := resolveAppliedScript(properties.root, path, match[1])
+				if !ok || applied == path {
+					continue
+				}
+				if _, known := table.projects[applied]; !known {
+					table.projects[applied] = table.projectOf(path)
+					changed = true
+				}
+			}
+		}
+	}
+
+	for _, path := range buildFilePaths {
+		for _, symbol := range parseVersionSymbols(path, contents[path]) {
+			if !symbol.Extra {
+				table.locals[path] = append(table.locals[path], symbol)
+				continue
+			}
+			project := table.projectOf(path)
+			if symbol.FromRoot {
+				project = properties.root
+			}
+			table.extras[project] = append(table.extras[project], symbol)
+		}
+		if dialectOf(path) == Groovy {
+			// entries of ext maps can be interpolated too, e.g. $versions.okhttp
+			extMaps := parseExtMaps(path, contents[path])
+			for _, member := range extMaps.Members {
+				if member.Value == "" {
+					continue
+				}
+				table.extras[table.projectOf(path)] = append(table.extras[table.projectOf(path)], VersionSymbol{
+					Name:     member.Reference,
+					Value:    member.Value,
+					Template: true,
+					Extra:    true,
+					Path:     path,
+					Line:     lineAt(contents[path], member.offset),
+				})
+			}
+		}
+	}
+	return table, nil
+}
+
+// addObjects makes the versions migrated from the dependency objects of buildSrc resolvable, e.g. ${Versions.okhttp}
+func (s *SymbolTable) addObjects(objects DependencyObjects) {
+	for _, file := range objects.Files {
+		if file.Dialect != Kotlin {
+			// ext maps are extra properties of the project applying them
+			continue
+		}
+		for _, member := range file.Members {
+			if member.Kind == ObjectVersion {
+				s.objects = append(s.objects, VersionSymbol{Name: member.Reference, Value: member.resolved, Path: file.Path})
+			}
+		}
+	}
+}
+
+// parseVersionSymbols collects the variables a build script defines with a string literal or reads from a property.
+func parseVersionSymbols(path string, content string) []VersionSymbol {
+	stripped := stripComments(content)
+	symbols := make([]VersionSymbol, 0)
+	collect := func(extractor *regexp.Regexp, text string, offset int, extra bool, owner string) {
+		for _, location := range extractor.FindAllStringSubmatchIndex(text, -1) {
+			group := func(name string) string {
+				for i, subexp := range extractor.SubexpNames() {
+					if subexp == name && location[2*i] >= 0 {
+						return content[offset+location[2*i] : offset+location[2*i+1]]
+					}
+				}
+				return ""
+			}
+			symbol := VersionSymbol{
+				Name:     group("name"),
+				Reads:    group("reads"),
+				Extra:    extra,
+				FromRoot: group("owner") == "rootProject" || owner == "rootProject",
+				Path:     path,
+				Line:     lineAt(content, offset+location[0]),
+			}
+			if template := group("template"); template != "" {
+				symbol.Value, symbol.Template = template, true
+			} else {
+				symbol.Value = group("literal")
+			}
+			if extractor == readerSymbolExtractors[0] {
+				symbol.Reads = symbol.Name
+			}
+			symbols = append(symbols, symbol)
+		}
+	}
+	collect(localSymbolExtractor, stripped, 0, false, "")
+	for _, extractor := range extraSymbolExtractors {
+		collect(extractor, stripped, 0, true, "")
+	}
+	for _, location := range extSymbolBlockExtractor.FindAllStringSubmatchIndex(stripped, -1) {
+		owner := ""
+		if location[2] >= 0 {
+			owner = stripped[location[2]:location[3]]
+		}
+		open := location[1] - 1
+		collect(extBlockSymbolExtractor, blockBody(stripped, open), open+1, true, owner)
+	}
+	for _, extractor := range readerSymbolExtractors {
+		collect(extractor, stripped, 0, false, "")
+	}
+	return symbols
+}
+
+func lineAt(content string, offset int) int {
+	return strings.Count(content[:offset], "\n") + 1
+}
+
+// projectOf returns the directory of the project the script at path is evaluated for.
+func (s *SymbolTable) projectOf(path string) string {
+	if project, ok := s.projects[path]; ok {
+		return project
+	}
+	return filepath.Dir(path)
+}
+
+// resolve returns the value the variable name has in the build script at path.
+func (s *SymbolTable) resolve(path string, name string) (Resolution, bool, error) {
+	resolution, ok, err := s.resolveIn(path, s.projectOf(path), name, 0)
+	if ok || err != nil || !strings.Contains(name, "_") {
+		return resolution, ok, err
+	}
+	for _, symbol := range s.objects {
+		if escapeVersionVariableName(symbol.Name) == name {
+			return s.evaluate(symbol, 0)
+		}
+	}
+	// ${rootProject.ext.okhttp} or ${versions.okhttp} are cataloged with underscores
+	dotted := strings.ReplaceAll(name, "_", ".")
+	for _, qualifier := range variableQualifiers {
+		if remainder, found := strings.CutPrefix(dotted, qualifier.prefix); found {
+			if qualifier.fromRoot {
+				return s.resolveIn("", s.properties.root, remainder, 0)
+			}
+			return s.resolveIn("", s.projectOf(path), remainder, 0)
+		}
+	}
+	return s.resolveIn(path, s.projectOf(path), dotted, 0)
+}
+
+// resolveIn looks name up in the local variables of the script at path, if any,
+// then in the extra properties and gradle.properties of the project at dir and of its parents.
+func (s *SymbolTable) resolveIn(path string, dir string, name string, depth int) (Resolution, bool, error) {
+	if depth > maxSymbolDepth {
+		return Resolution{}, false, nil
+	}
+	for _, symbol := range s.locals[path] {
+		if symbol.Name == name {
+			return s.evaluate(symbol, depth)
+		}
+	}
+	for _, directory := range s.properties.directories(dir) {
+		for _, symbol := range s.extras[directory] {
+			if symbol.Name == name {
+				return s.evaluate(symbol, depth)
+			}
+		}
+		file := filepath.Join(directory, "gradle.properties")
+		if value, ok, err := s.properties.property(file, name); err != nil || ok {
+			return Resolution{Value: value, Source: VersionVariable{Name: name, Path: file}}, ok, err
+		}
+	}
+	for _, file := range s.properties.extra {
+		if value, ok, err := s.properties.property(file, name); err != nil || ok {
+			return Resolution{Value: value, Source: VersionVariable{Name: name, Path: file}}, ok, err
+		}
+	}
+	return Resolution{}, false, nil
+}
+
+func (s *SymbolTable) evaluate(symbol VersionSymbol, depth int) (Resolution, bool, error) {
+	if symbol.Reads != "" {
+		dir := s.projectOf(symbol.Path)
+		if symbol.FromRoot {
+			dir = s.properties.root
+		}
+		return s.resolveIn("", dir, symbol.Reads, depth+1)
+	}
+	value := symbol.Value
+	if symbol.Template {
+		var failure error
+		resolved := true
+		value = templateReferenceExtractor.ReplaceAllStringFunc(value, func(reference string) string {
+			match := templateReferenceExtractor.FindStringSubmatch(reference)
+			name := match[1] + match[2]
+			if !identifierExtractor.MatchString(name) {
+				resolved = false
+				return reference
+			}
+			referenced, ok, err := s.resolveIn(symbol.Path, s.projectOf(symbol.Path), name, depth+1)
+			if err != nil {
+				failure = err
+			}
+			resolved = resolved && ok
+			return referenced.Value
+		})
+		if failure != nil || !resolved {
+			return Resolution{}, false, failure
+		}
+	}
+	return Resolution{Value: value, Source: VersionVariable{Name: symbol.Name, Path: symbol.Path}}, true, nil
+}
@@ -5,13 +5,15 @@ import (
 	"github.com/BurntSushi/toml"
 	"maps"
 	"os"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
 )
 
 type (
-	Versions  = map[string]string
+	// Versions holds plain versions as strings and rich versions as LooseLibrary
+	Versions  = map[string]any
 	Libraries = map[string]LooseLibrary
 	Plugins   = map[string]Plugin
 	Bundles   = map[string][]string
@@ -34,9 +36,31 @@ type (
 		Group   string
 		Name    string
 		Version string
+		// Declared is the version as written in the build file, e.g. "$guavaVersion" or '32.0', which rewrites find the alias by.
+		Declared string
+		// Rich is the rich version of the declaration, if any, e.g. strictly and prefer; Version then holds the one compared.
+		Rich LooseLibrary
+		// Path is the build file declaring the library.
+		Path string
+		// Configuration is the one the library is declared in, e.g. testImplementation.
+		Configuration string
+		// SourceSet is the Kotlin Multiplatform source set declaring the library, if any, e.g. commonMain.
+		SourceSet string
 	}
 )
 
+func (lib StrictLibrary) coordinate() string {
+	return lib.Group + ":" + lib.Name
+}
+
+// catalogVersion returns the version as written in the catalog: a rich version, a [versions] reference or a plain version.
+func (lib StrictLibrary) catalogVersion() any {
+	if lib.Rich != nil {
+		return lib.Rich
+	}
+	return toCatalogVersion(lib.Version)
+}
+
 func ReadCatalog(path string) (*VersionCatalog, error) {
 	if _, err := os.Stat(path); err != nil {
 		init := initVersionCatalog()
@@ -47,44 +71,90 @@ func ReadCatalog(path string) (*VersionCatalog, error) {
 	if err != nil {
 		return nil, err
 	}
+	// sections missing in the file are decoded as nil
+	if catalog.Versions == nil {
+		catalog.Versions = make(Versions)
+	}
+	if catalog.Libraries == nil {
+		catalog.Libraries = make(Libraries)
+	}
+	if catalog.Plugins == nil {
+		catalog.Plugins = make(Plugins)
+	}
+	if catalog.Bundles == nil {
+		catalog.Bundles = make(Bundles)
+	}
 	return catalog, nil
 }
 
 func WriteCatalog(path string, catalog VersionCatalog) error {
+	return os.WriteFile(path, []byte(formatCatalog(catalog)), 0644)
+}
+
+func formatCatalog(catalog VersionCatalog) string {
 	var builder strings.Builder
 	builder.WriteString(writeVersions(catalog.Versions))
 	builder.WriteString(writeLibraries(catalog.Libraries))
 	builder.WriteString(writeBundles(catalog.Bundles))
 	builder.WriteString(writePlugins(catalog.Plugins))
-	return os.WriteFile(path, []byte(builder.String()), 0644)
+	return builder.String()
 }
 
-func writeBuildSrcSettings(path string) error {
-	_, err := os.Stat(path)
+// writeCatalogSettings registers the catalogs at catalogPaths, keyed by name, in the settings file at path,
+// inside its versionCatalogs { } block if it has one, and tells whether the file changed.
+func writeCatalogSettings(path string, catalogPaths map[string]string, changes *ChangeSet) (bool, error) {
+	prevContent, err := changes.read(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
 
-	var prevContent string
-	if err == nil {
-		bytes, err := os.ReadFile(path)
+	var entries strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(catalogPaths)) {
+		relativePath, err := filepath.Rel(filepath.Dir(path), catalogPaths[name])
 		if err != nil {
-			return fmt.Errorf("failed to read %s: %w", path, err)
+			return false, err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+		if strings.Contains(prevContent, relativePath) {
+			fmt.Printf("NOTICE: The file %s already registers %s, skipping writing it.%s", path, relativePath, LineBreak)
+			continue
 		}
-		prevContent = string(bytes)
-		if strings.Contains(prevContent, "../gradle/libs.versions.toml") {
-			fmt.Printf("NOTICE: The file %s already contains the dependency resolution management block, skipping writing it.%s", path, LineBreak)
-			return nil
+		if dialectOf(path) == Kotlin {
+			entries.WriteString(fmt.Sprintf(`        create("%s") {%s`, name, LineBreak))
+			entries.WriteString(fmt.Sprintf(`            from(files("%s"))%s`, relativePath, LineBreak))
+		} else {
+			entries.WriteString(fmt.Sprintf(`        %s {%s`, name, LineBreak))
+			entries.WriteString(fmt.Sprintf(`            from(files('%s'))%s`, relativePath, LineBreak))
 		}
+		entries.WriteString(fmt.Sprintf(`        }%s`, LineBreak))
+	}
+	if entries.Len() == 0 {
+		return false, nil
+	}
+
+	stripped := stripComments(prevContent)
+	if location := versionCatalogsExtractor.FindStringIndex(stripped); location != nil {
+		// the closing brace of the existing block, which the entries go before
+		open := location[1] - 1
+		end := open + 1 + len(blockBody(stripped, open))
+		lineStart := strings.LastIndexByte(prevContent[:end], '\n') + 1
+		if strings.TrimSpace(prevContent[lineStart:end]) == "" {
+			changes.write(path, prevContent[:lineStart]+entries.String()+prevContent[lineStart:])
+		} else {
+			changes.write(path, prevContent[:end]+LineBreak+entries.String()+prevContent[end:])
+		}
+		return true, nil
 	}
 
 	var builder strings.Builder
 	builder.WriteString(prevContent)
 	builder.WriteString(fmt.Sprintf(`dependencyResolutionManagement {%s`, LineBreak))
 	builder.WriteString(fmt.Sprintf(`    versionCatalogs { %s`, LineBreak))
-	builder.WriteString(fmt.Sprintf(`        create("libs") {%s`, LineBreak))
-	builder.WriteString(fmt.Sprintf(`            from(files("../gradle/libs.versions.toml"))%s`, LineBreak))
-	builder.WriteString(fmt.Sprintf(`        }%s`, LineBreak))
+	builder.WriteString(entries.String())
 	builder.WriteString(fmt.Sprintf(`    }%s`, LineBreak))
 	builder.WriteString(fmt.Sprintf("}%s", LineBreak))
-	return os.WriteFile(path, []byte(builder.String()), 0644)
+	changes.write(path, builder.String())
+	return true, nil
 }
 
 func writeVersions(versions Versions) string {
@@ -97,13 +167,24 @@ func writeVersions(versions Versions) string {
 	for _, k := range slices.Sorted(maps.Keys(versions)) {
 		builder.WriteString(k)
 		builder.WriteString(" = ")
-		builder.WriteString(strconv.Quote(versions[k]))
+		builder.WriteString(strings.TrimPrefix(writeVersionEntry(versions[k]), ", version = "))
 		builder.WriteString(LineBreak)
 	}
 	builder.WriteString(LineBreak)
 	return builder.String()
 }
 
+// plainVersion returns a [versions] value as a plain version, the one compared for a rich version.
+func plainVersion(version any) string {
+	switch v := version.(type) {
+	case string:
+		return v
+	case LooseLibrary:
+		return representativeVersion(v)
+	}
+	return ""
+}
+
 func writeLibraries(libraries Libraries) string {
 	if len(libraries) <= 0 {
 		return ""
@@ -152,18 +233,35 @@ func writeVersionEntry(versionContainer any) string {
 
 		written := false
 		for _, vk := range []string{"ref", "strictly", "prefer", "require", "reject"} {
-			if v, ok := version[vk].(string); ok {
-				if written {
-					builder.WriteString(", ")
+			var value string
+			switch v := version[vk].(type) {
+			case string:
+				value = strconv.Quote(v)
+			case []string:
+				value = quoteList(v)
+			case []any:
+				// as decoded from an existing catalog
+				values := make([]string, len(v))
+				for i, item := range v {
+					values[i] = fmt.Sprint(item)
 				}
-				written = true
-				builder.WriteString(vk)
-				builder.WriteString(" = ")
-				builder.WriteString(strconv.Quote(v))
+				value = quoteList(values)
+			default:
+				continue
 			}
+			if written {
+				builder.WriteString(", ")
+			}
+			written = true
+			builder.WriteString(vk)
+			builder.WriteString(" = ")
+			builder.WriteString(value)
 		}
 		if rejectAll, ok := version["rejectAll"].(bool); ok {
-			builder.WriteString(", rejectAll = ")
+			if written {
+				builder.WriteString(", ")
+			}
+			builder.WriteString("rejectAll = ")
 			builder.WriteString(strconv.FormatBool(rejectAll))
 		}
 		builder.WriteString(" }")
@@ -172,6 +270,14 @@ func writeVersionEntry(versionContainer any) string {
 	return ""
 }
 
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
 func writeBundles(bundles Bundles) string {
 	if len(bundles) == 0 {
 		return ""
@@ -180,15 +286,9 @@ func writeBundles(bundles Bundles) string {
 	builder.WriteString("[bundles]")
 	builder.WriteString(LineBreak)
 	for _, k := range slices.Sorted(maps.Keys(bundles)) {
-		v := bundles[k]
-		quoted := make([]string, len(v))
-		for i, s := range v {
-			quoted[i] = strconv.Quote(s)
-		}
 		builder.WriteString(k)
-		builder.WriteString(" = [")
-		builder.WriteString(strings.Join(quoted, ", "))
-		builder.WriteString("]")
+		builder.WriteString(" = ")
+		builder.WriteString(quoteList(bundles[k]))
 		builder.WriteString(LineBreak)
 	}
 	builder.WriteString(LineBreak)